@@ -80,6 +80,7 @@ labelValueLengthLimit: {{ . }}
 prometheusremotewrite:
   endpoint: {{ .kof.metrics.endpoint }}
   {{- include "kof-collectors.helper.tls_options" .kof.metrics | indent 2 }}
+  {{- include "kof-collectors.helper.headers" .kof.metrics | indent 2 }}
   {{- if .kof.basic_auth }}
   auth:
     authenticator: basicauth/metrics
@@ -92,6 +93,7 @@ otlphttp:
     authenticator: basicauth/logs
   {{- end }}
   {{- include "kof-collectors.helper.tls_options" .kof.logs | indent 2 }}
+  {{- include "kof-collectors.helper.headers" .kof.logs | indent 2 }}
   logs_endpoint: {{ .kof.logs.endpoint }}
 {{- end }}
 {{- end }}
@@ -111,11 +113,13 @@ prometheus:
 otlphttp/traces:
   endpoint: {{ .kof.traces.endpoint }}
   {{- include "kof-collectors.helper.tls_options" .kof.traces | indent 2 }}
+  {{- include "kof-collectors.helper.headers" .kof.traces | indent 2 }}
 {{- end }}
 {{- if .kof.metrics.endpoint }}
 prometheusremotewrite:
   endpoint: {{ .kof.metrics.endpoint }}
   {{- include "kof-collectors.helper.tls_options" .kof.metrics | indent 2 }}
+  {{- include "kof-collectors.helper.headers" .kof.metrics | indent 2 }}
   {{- if .kof.basic_auth }}
   auth:
     authenticator: basicauth/metrics
@@ -129,6 +133,43 @@ otlphttp/logs:
   {{- end }}
   logs_endpoint: {{ .kof.logs.endpoint }}
   {{- include "kof-collectors.helper.tls_options" .kof.logs | indent 2 }}
+  {{- include "kof-collectors.helper.headers" .kof.logs | indent 2 }}
+{{- end }}
+{{- end }}
+
+{{- /* Exporters to additional regional clusters, named by `.prefixes` of signals. */ -}}
+{{- define "additional_exporters" }}
+{{- range $name, $endpoints := .kof.additional_endpoints }}
+{{- range $signal, $prefix := $.prefixes }}
+{{- $options := index $endpoints $signal }}
+{{- if and $options $options.endpoint }}
+{{ $prefix }}{{ $name }}:
+  {{- if eq $signal "logs" }}
+  logs_endpoint: {{ $options.endpoint }}
+  {{- else }}
+  endpoint: {{ $options.endpoint }}
+  {{- end }}
+  {{- include "kof-collectors.helper.tls_options" $options | indent 2 }}
+  {{- include "kof-collectors.helper.headers" $options | indent 2 }}
+  {{- if and $.kof.basic_auth (ne $signal "traces") }}
+  auth:
+    authenticator: basicauth/{{ $signal }}
+  {{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+
+{{- /* Adds the exporters of "additional_exporters" to the pipelines of `.service`. */ -}}
+{{- define "additional_pipeline_exporters" }}
+{{- range $name, $endpoints := .kof.additional_endpoints }}
+{{- range $signal, $prefix := $.prefixes }}
+{{- $options := index $endpoints $signal }}
+{{- $pipeline := index $.service.pipelines $signal }}
+{{- if and $options $options.endpoint $pipeline }}
+{{- $_ := set $pipeline "exporters" (append $pipeline.exporters (printf "%s%s" $prefix $name)) }}
+{{- end }}
+{{- end }}
 {{- end }}
 {{- end }}
 
@@ -157,6 +198,12 @@ basicauth/{{ . }}:
 {{- end }}
 {{- end }}
 
+{{- define "kof-collectors.helper.headers" -}}
+{{- with .headers }}
+headers: {{ . | toYaml | nindent 2 }}
+{{- end }}
+{{- end }}
+
 {{- define "kof-collectors.helper.tls_options" -}}
 {{- $parsedEndpoint := urlParse .endpoint }} 
 {{- if eq $parsedEndpoint.scheme "http" }}
@@ -62,4 +62,20 @@ Create the name of the service account to use
 {{- else -}}
     {{ default "default" .Values.kcm.kof.operator.serviceAccount.name }}
 {{- end -}}
-{{- end -}}
\ No newline at end of file
+{{- end -}}
+
+{{/*
+Webhooks require cert-manager to issue the serving certificate
+*/}}
+{{- define "operator.webhooksEnabled" -}}
+{{- if and .Values.kcm.kof.operator.webhooks.enabled (index .Values "cert-manager" "enabled") -}}
+true
+{{- end -}}
+{{- end -}}
+
+{{/*
+Name of the webhook Service, Certificate and its Secret
+*/}}
+{{- define "operator.webhookName" -}}
+{{ include "operator.fullname" . }}-kof-operator-webhook
+{{- end -}}
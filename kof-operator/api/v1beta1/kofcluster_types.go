@@ -0,0 +1,107 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types of KofCluster
+const (
+	// KofClusterReconciledCondition reports whether the kof objects of the cluster are reconciled
+	KofClusterReconciledCondition = "Reconciled"
+	// KofClusterReadyCondition mirrors the Ready condition of the ClusterDeployment
+	KofClusterReadyCondition = "ClusterReady"
+	// KofRegionalClusterReadyCondition mirrors the Ready condition of the regional ClusterDeployment of a child
+	KofRegionalClusterReadyCondition = "RegionalClusterReady"
+	// KofClusterEndpointsReachableCondition reports the last probe of the endpoints of a child cluster,
+	// set only if the endpoint probe of the operator is enabled
+	KofClusterEndpointsReachableCondition = "EndpointsReachable"
+)
+
+// KofClusterEndpoints are the resolved endpoints of the regional cluster,
+// written to the ConfigMap of a child cluster or used by promxy and Grafana for a regional cluster
+type KofClusterEndpoints struct {
+	ReadMetrics  string `json:"readMetrics,omitempty"`
+	WriteMetrics string `json:"writeMetrics,omitempty"`
+	ReadLogs     string `json:"readLogs,omitempty"`
+	WriteLogs    string `json:"writeLogs,omitempty"`
+	WriteTraces  string `json:"writeTraces,omitempty"`
+	ReadTraces   string `json:"readTraces,omitempty"`
+}
+
+// KofClusterIstioStatus is the Istio setup of the cluster
+type KofClusterIstioStatus struct {
+	// Role is the value of the istio-role label
+	Role string `json:"role"`
+	// RemoteSecretName is the name of the Istio remote Secret of a child cluster, if it is created
+	RemoteSecretName string `json:"remoteSecretName,omitempty"`
+}
+
+// KofClusterStatus defines the observed observability wiring of the ClusterDeployment
+type KofClusterStatus struct {
+	// ObservedGeneration is the last generation of the ClusterDeployment reconciled
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Role is the kof cluster role: child or regional
+	Role string `json:"role,omitempty"`
+	// RegionalClusterName is the regional cluster the child cluster sends telemetry to
+	RegionalClusterName string `json:"regionalClusterName,omitempty"`
+	// Tenant of the child cluster, empty for the default tenant
+	Tenant string `json:"tenant,omitempty"`
+	// AdditionalRegionalClusterNames are the regional clusters the child cluster also sends telemetry to
+	AdditionalRegionalClusterNames []string `json:"additionalRegionalClusterNames,omitempty"`
+	// Endpoints are the resolved endpoints of the regional cluster
+	Endpoints KofClusterEndpoints `json:"endpoints,omitempty"`
+	// Istio is set if the cluster has the istio-role label
+	Istio *KofClusterIstioStatus `json:"istio,omitempty"`
+	// Conditions are Reconciled, ClusterReady, RegionalClusterReady and EndpointsReachable
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Role",type=string,JSONPath=`.status.role`
+// +kubebuilder:printcolumn:name="Regional",type=string,JSONPath=`.status.regionalClusterName`
+// +kubebuilder:printcolumn:name="Istio",type=string,JSONPath=`.status.istio.role`
+// +kubebuilder:printcolumn:name="Reconciled",type=string,JSONPath=`.status.conditions[?(@.type=="Reconciled")].status`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="ClusterReady")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// KofCluster is the Schema for the kofclusters API.
+// It is a read-only summary of the ClusterDeployment with the same name,
+// maintained by the operator and deleted with the ClusterDeployment.
+type KofCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status KofClusterStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KofClusterList contains a list of KofCluster
+type KofClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KofCluster `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KofCluster{}, &KofClusterList{})
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PromxyConfigSpec defines the global settings of the promxy config.
+// Unset fields keep the defaults of the kof-mothership chart.
+type PromxyConfigSpec struct {
+	// EvaluationInterval of the rules in the string representation (e.g. 5s)
+	EvaluationInterval metav1.Duration `json:"evaluation_interval,omitempty"`
+	// ExternalLabels are added to the alerts and the written series
+	ExternalLabels map[string]string `json:"external_labels,omitempty"`
+	// RemoteWrite targets of the recording rules
+	RemoteWrite []RemoteWriteConfig `json:"remote_write,omitempty"`
+	// Alertmanagers receiving the alerts
+	Alertmanagers []AlertmanagerConfig `json:"alertmanagers,omitempty"`
+}
+
+// RemoteWriteConfig part of prometheus remote_write config with json annotation
+type RemoteWriteConfig struct {
+	URL            string `json:"url"`
+	HTTPAuthConfig `json:",inline"`
+}
+
+// AlertmanagerConfig part of prometheus alertmanager_config with json annotation
+type AlertmanagerConfig struct {
+	// Targets address:port list for alertmanager static_configs
+	// +kubebuilder:validation:MinItems=1
+	Targets []string `json:"targets"`
+	// PathPrefix defines path_prefix for all targets
+	PathPrefix string `json:"path_prefix,omitempty"`
+	// Scheme for all targets (http or https)
+	Scheme         string `json:"scheme,omitempty"`
+	HTTPAuthConfig `json:",inline"`
+}
+
+// HTTPAuthConfig defines the TLS and auth config of the requests sent by promxy.
+// At most one of BasicAuth, BearerToken and OAuth2 may be set.
+type HTTPAuthConfig struct {
+	TLSConfig   TLSConfig    `json:"tls_config,omitempty"`
+	BasicAuth   BasicAuth    `json:"basic_auth,omitempty"`
+	BearerToken *BearerToken `json:"bearer_token,omitempty"`
+	OAuth2      *OAuth2      `json:"oauth2,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PromxyConfig is the Schema for the promxyconfigs API.
+// It is merged with the server groups rendered into the promxy config Secret with the same name.
+type PromxyConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PromxyConfigSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PromxyConfigList contains a list of PromxyConfig
+type PromxyConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PromxyConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PromxyConfig{}, &PromxyConfigList{})
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -29,19 +30,120 @@ type PromxyServerGroupSpec struct {
 	ClusterName string `json:"cluster_name,omitempty"`
 	// Targets address:port list for promxy Prometheus server group static_configs
 	Targets []string `json:"targets,omitempty"`
+	// DNSSDConfigs discover targets via DNS SRV, A or AAAA records
+	DNSSDConfigs []DNSSDConfig `json:"dns_sd_configs,omitempty"`
+	// KubernetesSDConfigs discover targets via the Kubernetes API
+	KubernetesSDConfigs []KubernetesSDConfig `json:"kubernetes_sd_configs,omitempty"`
 	// PathPrefix defines path_prefix for all targets
 	PathPrefix string `json:"path_prefix,omitempty"`
 	// Scheme for all targets (http or https)
 	Scheme     string           `json:"scheme,omitempty"`
 	HttpClient HTTPClientConfig `json:"http_client,omitempty"`
+	// Labels are added to the series of the group, promxyCluster label is always set to ClusterName
+	Labels map[string]string `json:"labels,omitempty"`
+	// RelabelConfigs are applied to the discovered targets
+	RelabelConfigs []RelabelConfig `json:"relabel_configs,omitempty"`
+	// QueryParams are added to the queries of the targets
+	QueryParams map[string]string `json:"query_params,omitempty"`
+	// RemoteRead makes promxy fetch raw data via the remote read API of the targets
+	RemoteRead bool `json:"remote_read,omitempty"`
+	// RemoteReadPath is the path of the remote read API, api/v1/read by default
+	RemoteReadPath string `json:"remote_read_path,omitempty"`
+	// AntiAffinity in the string representation (e.g. 10s)
+	// is the time between points from different targets merged into one series
+	AntiAffinity metav1.Duration `json:"anti_affinity,omitempty"`
+	// Timeout of the queries to the targets in the string representation (e.g. 30s)
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+	// IgnoreError returns partial data if the group fails, true by default.
+	// Set it to false to fail the queries loudly instead.
+	IgnoreError *bool `json:"ignore_error,omitempty"`
 }
 
-// HTTPClientConfig defines the http client TLS and BasicAuth config for Prometheus
+// RelabelConfig part of prometheus relabel_config with json annotation
+type RelabelConfig struct {
+	SourceLabels []string `json:"source_labels,omitempty"`
+	Separator    string   `json:"separator,omitempty"`
+	// Regex is anchored at both ends, (.*) by default
+	Regex       string `json:"regex,omitempty"`
+	Modulus     uint64 `json:"modulus,omitempty"`
+	TargetLabel string `json:"target_label,omitempty"`
+	// Replacement is $1 by default
+	Replacement string `json:"replacement,omitempty"`
+	// Action is replace by default
+	// +kubebuilder:validation:Enum=replace;keep;drop;keepequal;dropequal;hashmod;labelmap;labeldrop;labelkeep;lowercase;uppercase
+	Action string `json:"action,omitempty"`
+}
+
+// DNSSDConfig part of prometheus dns_sd_config with json annotation
+type DNSSDConfig struct {
+	// Names are the DNS names to query
+	// +kubebuilder:validation:MinItems=1
+	Names []string `json:"names"`
+	// Type of the DNS records, SRV by default
+	// +kubebuilder:validation:Enum=SRV;A;AAAA
+	Type string `json:"type,omitempty"`
+	// Port of the targets, required for A and AAAA records
+	Port int32 `json:"port,omitempty"`
+	// RefreshInterval in the string representation (e.g. 30s)
+	RefreshInterval metav1.Duration `json:"refresh_interval,omitempty"`
+}
+
+// KubernetesSDConfig part of prometheus kubernetes_sd_config with json annotation.
+// Every port of the discovered objects becomes a target, use RelabelConfigs to select one.
+// In-cluster discovery needs RBAC for the promxy service account.
+type KubernetesSDConfig struct {
+	// Role of the discovered objects
+	// +kubebuilder:validation:Enum=endpoints;endpointslice;pod;service
+	Role string `json:"role"`
+	// Namespaces to discover objects in, all namespaces by default
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Selectors limit the discovered objects by labels and fields
+	Selectors []KubernetesSDSelector `json:"selectors,omitempty"`
+	// APIServer is the URL of the Kubernetes API, in-cluster config is used by default
+	APIServer string `json:"api_server,omitempty"`
+	// KubeconfigFile is the path to the kubeconfig in the promxy pod,
+	// e.g. to discover objects of a regional cluster
+	KubeconfigFile string `json:"kubeconfig_file,omitempty"`
+}
+
+// KubernetesSDSelector part of prometheus kubernetes_sd_config selectors with json annotation
+type KubernetesSDSelector struct {
+	// +kubebuilder:validation:Enum=endpoints;endpointslice;pod;service
+	Role  string `json:"role"`
+	Label string `json:"label,omitempty"`
+	Field string `json:"field,omitempty"`
+}
+
+// HTTPClientConfig defines the http client TLS and auth config for Prometheus.
+// At most one of BasicAuth, BearerToken and OAuth2 may be set.
 type HTTPClientConfig struct {
 	// DialTimeout in the string representation (e.g. 1s)
 	DialTimeout metav1.Duration `json:"dial_timeout,omitempty"`
 	TLSConfig   TLSConfig       `json:"tls_config,omitempty"`
 	BasicAuth   BasicAuth       `json:"basic_auth,omitempty"`
+	BearerToken *BearerToken    `json:"bearer_token,omitempty"`
+	OAuth2      *OAuth2         `json:"oauth2,omitempty"`
+}
+
+// BearerToken part of prometheus HTTPClientConfig `authorization` with json annotation.
+// Exactly one of Secret and File should be set.
+type BearerToken struct {
+	// Secret selects the token in a Secret
+	Secret *corev1.SecretKeySelector `json:"secret,omitempty"`
+	// File is the path to the token in the promxy pod,
+	// e.g. a projected service account token
+	File string `json:"file,omitempty"`
+}
+
+// OAuth2 part of prometheus HTTPClientConfig with json annotation,
+// uses the client credentials grant
+type OAuth2 struct {
+	ClientID string `json:"client_id"`
+	// ClientSecret selects the client secret in a Secret
+	ClientSecret   corev1.SecretKeySelector `json:"client_secret"`
+	TokenURL       string                   `json:"token_url"`
+	Scopes         []string                 `json:"scopes,omitempty"`
+	EndpointParams map[string]string        `json:"endpoint_params,omitempty"`
 }
 
 // BasicAuth part of prometheus HTTPClientConfig with json annotation
@@ -54,16 +156,53 @@ type BasicAuth struct {
 // TLSConfig part of prometheus HTTPClientConfig with json annotation
 type TLSConfig struct {
 	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+	// ServerName is used to verify the hostname of the targets
+	ServerName string `json:"server_name,omitempty"`
+	// CA bundle used to verify the certificates of the targets
+	CA *SecretOrConfigMap `json:"ca,omitempty"`
+	// Cert is the client certificate for mTLS
+	Cert *SecretOrConfigMap `json:"cert,omitempty"`
+	// KeySecret is the client key for mTLS
+	KeySecret *corev1.SecretKeySelector `json:"key_secret,omitempty"`
 }
 
+// SecretOrConfigMap selects a key of either a Secret or a ConfigMap
+// in the namespace of the referencing object
+type SecretOrConfigMap struct {
+	Secret    *corev1.SecretKeySelector    `json:"secret,omitempty"`
+	ConfigMap *corev1.ConfigMapKeySelector `json:"config_map,omitempty"`
+}
+
+// Condition types of PromxyServerGroup
+const (
+	// RenderedCondition reports whether the group is rendered into the promxy config Secret
+	RenderedCondition = "Rendered"
+	// ReloadedCondition reports whether promxy is reloaded with the rendered config
+	ReloadedCondition = "Reloaded"
+	// CredentialsResolvedCondition reports whether the referenced credentials are read
+	CredentialsResolvedCondition = "CredentialsResolved"
+)
+
 // PromxyServerGroupStatus defines the observed state of PromxyServerGroup
 type PromxyServerGroupStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// ObservedGeneration is the last generation of the group rendered into promxy config
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// SecretName is the name of the promxy config Secret the group is rendered into
+	SecretName string `json:"secretName,omitempty"`
+	// ConfigHash is the sha256 hash of the rendered promxy config
+	ConfigHash string `json:"configHash,omitempty"`
+	// Conditions are Rendered, Reloaded and CredentialsResolved
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.status.secretName`
+// +kubebuilder:printcolumn:name="Rendered",type=string,JSONPath=`.status.conditions[?(@.type=="Rendered")].status`
+// +kubebuilder:printcolumn:name="Reloaded",type=string,JSONPath=`.status.conditions[?(@.type=="Reloaded")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // PromxyServerGroup is the Schema for the promxyservergroups API
 type PromxyServerGroup struct {
@@ -85,6 +85,8 @@ func main() {
 	var runController bool
 	var remoteWriteUrl string
 	var promxyReloadEnpoint string
+	var promxyServiceName string
+	var endpointProbeInterval time.Duration
 	var enableServerCORS bool
 	var httpServerAddr string
 	var tlsOpts []func(*tls.Config)
@@ -108,7 +110,19 @@ func main() {
 		&promxyReloadEnpoint,
 		"promxy-reload-endpoint",
 		"http://localhost:8082/-/reload",
-		"The promxy config reload endpoint",
+		"The promxy config reload endpoint, used if --promxy-service-name is not set",
+	)
+	flag.StringVar(
+		&promxyServiceName,
+		"promxy-service-name",
+		"",
+		"The promxy Service in the release namespace, all replicas behind it are reloaded",
+	)
+	flag.DurationVar(
+		&endpointProbeInterval,
+		"endpoint-probe-interval",
+		0,
+		"The interval of probing the endpoints of child clusters from the management cluster, 0 disables the probe",
 	)
 	flag.BoolVar(&enableServerCORS, "enable-cors", true, "Enable CORS for local development (allows all origins)")
 	flag.BoolVar(&runController, "run-controller", true, "Run controller manager")
@@ -128,9 +142,20 @@ func main() {
 	if endpoint, ok := os.LookupEnv("PROMXY_RELOAD_ENDPOINT"); ok {
 		promxyReloadEnpoint = endpoint
 	}
+	if serviceName, ok := os.LookupEnv("PROMXY_SERVICE_NAME"); ok {
+		promxyServiceName = serviceName
+	}
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if interval, ok := os.LookupEnv("ENDPOINT_PROBE_INTERVAL"); ok && interval != "" {
+		var err error
+		if endpointProbeInterval, err = time.ParseDuration(interval); err != nil {
+			setupLog.Error(err, "invalid ENDPOINT_PROBE_INTERVAL env var")
+			os.Exit(1)
+		}
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -225,20 +250,36 @@ func main() {
 
 	record.InitFromRecorder(mgr.GetEventRecorderFor("kof-operator"))
 
+	promxyReloader := controller.NewPromxyReloader(
+		mgr.GetClient(),
+		os.Getenv("RELEASE_NAMESPACE"),
+		promxyServiceName,
+		controller.GetServedPromxySecretName(),
+		promxyReloadEnpoint,
+	)
 	if err = (&controller.PromxyServerGroupReconciler{
 		Client:             mgr.GetClient(),
 		Scheme:             mgr.GetScheme(),
 		RemoteWriteUrl:     remoteWriteUrl,
-		PromxyConfigReload: func() error { return controller.ReloadPromxyConfig(promxyReloadEnpoint) },
+		PromxyConfigReload: promxyReloader.Reload,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "PromxyServerGroup")
 		os.Exit(1)
 	}
+	var endpointProber *controller.EndpointProber
+	if endpointProbeInterval > 0 {
+		endpointProber = controller.NewEndpointProber(mgr.GetClient(), endpointProbeInterval)
+		if err = mgr.Add(endpointProber); err != nil {
+			setupLog.Error(err, "unable to add endpoint prober")
+			os.Exit(1)
+		}
+	}
 	if err = (&controller.ClusterDeploymentReconciler{
 		Client:              mgr.GetClient(),
 		Scheme:              mgr.GetScheme(),
 		IstioCertManager:    cert.New(mgr.GetClient()),
 		RemoteSecretManager: remotesecret.New(mgr.GetClient()),
+		EndpointProber:      endpointProber,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ClusterDeployment")
 		os.Exit(1)
@@ -250,6 +291,19 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "ConfigMap")
 		os.Exit(1)
 	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&controller.PromxyServerGroupWebhook{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PromxyServerGroup")
+			os.Exit(1)
+		}
+		if err = (&controller.ClusterDeploymentWebhook{
+			Reader: mgr.GetAPIReader(),
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ClusterDeployment")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
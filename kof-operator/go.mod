@@ -9,7 +9,9 @@ require (
 	github.com/onsi/ginkgo/v2 v2.23.4
 	github.com/onsi/gomega v1.37.0
 	github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring v0.82.0
+	github.com/prometheus/common v0.63.0
 	golang.org/x/net v0.40.0 // indirect; https://github.com/k0rdent/kof/security/dependabot/13
+	gopkg.in/yaml.v2 v2.4.0
 	gopkg.in/yaml.v3 v3.0.1
 	helm.sh/helm/v3 v3.17.3 // indirect; https://github.com/k0rdent/kof/security/dependabot/11
 	k8s.io/api v0.33.0
@@ -165,7 +167,6 @@ require (
 	golang.org/x/crypto v0.38.0 // indirect
 	golang.org/x/mod v0.24.0 // indirect
 	google.golang.org/api v0.224.0 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
 	istio.io/api v1.25.0-alpha.0.0.20250227144231-affcb4000ed2 // indirect
 	istio.io/client-go v1.25.0-alpha.0.0.20250227171830-489349726dc9 // indirect
 	k8s.io/cli-runtime v0.33.0 // indirect
@@ -227,7 +228,6 @@ require (
 	github.com/projectsveltos/libsveltos v0.54.0 // indirect
 	github.com/prometheus/client_golang v1.22.0 // indirect
 	github.com/prometheus/client_model v0.6.1 // indirect
-	github.com/prometheus/common v0.63.0 // indirect
 	github.com/prometheus/procfs v0.15.1 // indirect
 	github.com/spf13/cobra v1.9.1 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
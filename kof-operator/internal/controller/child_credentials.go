@@ -0,0 +1,344 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"strconv"
+
+	kcmv1beta1 "github.com/K0rdent/kcm/api/v1beta1"
+	"github.com/k0rdent/kof/kof-operator/internal/controller/utils"
+	sveltosv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+)
+
+// Opts the child cluster in to its own vmauth credentials instead of the shared `KofStorageSecretName`.
+const KofChildCredentialsAnnotation = prefix + "kof-child-credentials"
+
+// Identifier of the credentials Secret in the templates of the child credentials Profiles.
+const childCredentialsIdentifier = "Credentials"
+
+// Returns true if the child opted in to its own vmauth credentials.
+func childCredentialsEnabled(childClusterDeployment *kcmv1beta1.ClusterDeployment) bool {
+	enabled, err := strconv.ParseBool(childClusterDeployment.Annotations[KofChildCredentialsAnnotation])
+	return err == nil && enabled
+}
+
+// Returns the name of the credentials Secret of the child cluster,
+// used for the Secret and the VMUser deployed to the clusters too.
+func childCredentialsName(childClusterName string) string {
+	return "kof-vmuser-" + childClusterName
+}
+
+// Returns the objects created by `reconcileChildCredentials`, for deletion.
+func childCredentialsObjects(childClusterDeployment *kcmv1beta1.ClusterDeployment) []struct {
+	object            client.Object
+	objectDescription string
+} {
+	name := childClusterDeployment.Name
+	namespace := childClusterDeployment.Namespace
+	return []struct {
+		object            client.Object
+		objectDescription string
+	}{
+		{&sveltosv1beta1.Profile{ObjectMeta: metav1.ObjectMeta{
+			Name:      name + "-kof-vmuser",
+			Namespace: namespace,
+		}}, "Profile"},
+		{&sveltosv1beta1.Profile{ObjectMeta: metav1.ObjectMeta{
+			Name:      name + "-kof-vmuser-credentials",
+			Namespace: namespace,
+		}}, "Profile"},
+		{&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:      "kof-vmuser-template-" + name,
+			Namespace: namespace,
+		}}, "VMUser template ConfigMap"},
+		{&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:      "kof-vmuser-secret-template-" + name,
+			Namespace: namespace,
+		}}, "credentials Secret template ConfigMap"},
+		{&corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:      childCredentialsName(name),
+			Namespace: namespace,
+		}}, "credentials Secret"},
+	}
+}
+
+// Creates the credentials Secret of the child cluster once, and the Profiles deploying it
+// with the VMUser to the `regionalClusterNames` the child writes to, and the Secret to the child,
+// into the namespace of the `storage` services the VMUser targets.
+// The objects are owned by the child ClusterDeployment, so the credentials are revoked
+// when it is deleted. Deleting the credentials Secret rotates them on the next reconciliation.
+// Returns the name of the credentials Secret for the child cluster ConfigMap.
+func (r *ClusterDeploymentReconciler) reconcileChildCredentials(
+	ctx context.Context,
+	ownerReference metav1.OwnerReference,
+	childClusterDeployment *kcmv1beta1.ClusterDeployment,
+	regionalClusterNames []string,
+	storage StorageServices,
+	tenant *Tenant,
+) (string, error) {
+	name := childClusterDeployment.Name
+	secretName := childCredentialsName(name)
+	objectMeta := func(objectName string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:            objectName,
+			Namespace:       childClusterDeployment.Namespace,
+			Labels:          map[string]string{utils.ManagedByLabel: utils.ManagedByValue},
+			OwnerReferences: []metav1.OwnerReference{ownerReference},
+		}
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: objectMeta(secretName),
+		Type:       corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			"username": []byte(secretName),
+			"password": []byte(rand.Text()),
+		},
+	}
+	if err := r.createIfNotExists(ctx, secret, "credentials Secret", []any{
+		"secretName", secret.Name,
+	}); err != nil {
+		utils.LogEvent(
+			ctx,
+			"ChildCredentialsCreationFailed",
+			"Failed to create credentials Secret of child cluster",
+			childClusterDeployment,
+			err,
+			"secretName", secret.Name,
+		)
+		return "", err
+	}
+
+	secretTemplate, err := yaml.Marshal(map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]any{"name": secretName, "namespace": storage.Namespace},
+		"type":       string(corev1.SecretTypeOpaque),
+		"data": map[string]any{
+			"username": `{{ getField "` + childCredentialsIdentifier + `" "data.username" }}`,
+			"password": `{{ getField "` + childCredentialsIdentifier + `" "data.password" }}`,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	vmUserTemplate, err := yaml.Marshal(getChildVMUser(secretName, storage, tenant))
+	if err != nil {
+		return "", err
+	}
+
+	secretTemplateConfigMap := &corev1.ConfigMap{
+		ObjectMeta: objectMeta("kof-vmuser-secret-template-" + name),
+		Data:       map[string]string{"secret.yaml": string(secretTemplate)},
+	}
+	secretTemplateConfigMap.Annotations = map[string]string{"projectsveltos.io/template": "true"}
+	vmUserTemplateConfigMap := &corev1.ConfigMap{
+		ObjectMeta: objectMeta("kof-vmuser-template-" + name),
+		Data:       map[string]string{"vmuser.yaml": string(vmUserTemplate)},
+	}
+	vmUserTemplateConfigMap.Annotations = map[string]string{"projectsveltos.io/template": "true"}
+
+	regionalClusterRefs := make([]corev1.ObjectReference, 0, len(regionalClusterNames))
+	for _, regionalClusterName := range regionalClusterNames {
+		regionalClusterRefs = append(regionalClusterRefs, corev1.ObjectReference{
+			APIVersion: clusterv1.GroupVersion.String(),
+			Kind:       clusterv1.ClusterKind,
+			Name:       regionalClusterName,
+			Namespace:  childClusterDeployment.Namespace,
+		})
+	}
+	childClusterRefs := []corev1.ObjectReference{{
+		APIVersion: clusterv1.GroupVersion.String(),
+		Kind:       clusterv1.ClusterKind,
+		Name:       name,
+		Namespace:  childClusterDeployment.Namespace,
+	}}
+
+	regionalProfile := &sveltosv1beta1.Profile{
+		ObjectMeta: objectMeta(name + "-kof-vmuser"),
+		Spec: getChildCredentialsProfileSpec(
+			regionalClusterRefs,
+			secret,
+			secretTemplateConfigMap,
+			vmUserTemplateConfigMap,
+		),
+	}
+	childProfile := &sveltosv1beta1.Profile{
+		ObjectMeta: objectMeta(name + "-kof-vmuser-credentials"),
+		Spec:       getChildCredentialsProfileSpec(childClusterRefs, secret, secretTemplateConfigMap),
+	}
+
+	for _, item := range []struct {
+		object            client.Object
+		objectDescription string
+	}{
+		{secretTemplateConfigMap, "credentials Secret template ConfigMap"},
+		{vmUserTemplateConfigMap, "VMUser template ConfigMap"},
+		{regionalProfile, "Profile"},
+		{childProfile, "Profile"},
+	} {
+		result, err := r.createOrUpdate(ctx, item.object, item.objectDescription, []any{
+			"objectName", item.object.GetName(),
+		})
+		if err != nil {
+			utils.LogEvent(
+				ctx,
+				"ChildCredentialsCreationFailed",
+				"Failed to create or update "+item.objectDescription+" of child credentials",
+				childClusterDeployment,
+				err,
+				"objectName", item.object.GetName(),
+			)
+			return "", err
+		}
+		switch result {
+		case controllerutil.OperationResultCreated:
+			utils.LogEvent(
+				ctx,
+				"ChildCredentialsObjectCreated",
+				"Created "+item.objectDescription+" of child credentials",
+				childClusterDeployment,
+				nil,
+				"objectName", item.object.GetName(),
+			)
+		case controllerutil.OperationResultUpdated:
+			utils.LogEvent(
+				ctx,
+				"ChildCredentialsObjectUpdated",
+				"Updated "+item.objectDescription+" of child credentials",
+				childClusterDeployment,
+				nil,
+				"objectName", item.object.GetName(),
+			)
+		}
+	}
+	return secretName, nil
+}
+
+// Returns the Profile spec deploying the templates of `configMaps` with the credentials `secret`.
+func getChildCredentialsProfileSpec(
+	clusterRefs []corev1.ObjectReference,
+	secret *corev1.Secret,
+	configMaps ...*corev1.ConfigMap,
+) sveltosv1beta1.Spec {
+	policyRefs := make([]sveltosv1beta1.PolicyRef, 0, len(configMaps))
+	for _, configMap := range configMaps {
+		policyRefs = append(policyRefs, sveltosv1beta1.PolicyRef{
+			Kind:           "ConfigMap",
+			Name:           configMap.Name,
+			Namespace:      configMap.Namespace,
+			DeploymentType: sveltosv1beta1.DeploymentTypeRemote,
+		})
+	}
+	return sveltosv1beta1.Spec{
+		ClusterRefs: clusterRefs,
+		TemplateResourceRefs: []sveltosv1beta1.TemplateResourceRef{
+			{
+				Identifier: childCredentialsIdentifier,
+				Resource: corev1.ObjectReference{
+					APIVersion: corev1.SchemeGroupVersion.Version,
+					Kind:       "Secret",
+					Name:       secret.Name,
+					Namespace:  secret.Namespace,
+				},
+			},
+		},
+		PolicyRefs: policyRefs,
+		// Defaults of the CRD are set explicitly to avoid updates on each reconciliation.
+		SyncMode:             sveltosv1beta1.SyncModeContinuous,
+		StopMatchingBehavior: sveltosv1beta1.WithdrawPolicies,
+		Tier:                 100,
+	}
+}
+
+// Returns the VMUser of the child cluster, like `vmuser-select` of kof-storage,
+// but limited to the write and read paths the child uses, in its `tenant` only,
+// or in the default tenant 0 for nil `tenant`, targeting the `storage` services.
+func getChildVMUser(secretName string, storage StorageServices, tenant *Tenant) map[string]any {
+	if tenant == nil {
+		tenant = &Tenant{}
+	}
+	metricsTenantID := tenant.metricsTenantID()
+	tenantHeaders := tenant.logsHeaders()
+	logsHeaders := []string{
+		"AccountID: " + tenantHeaders["AccountID"],
+		"ProjectID: " + tenantHeaders["ProjectID"],
+	}
+
+	return map[string]any{
+		"apiVersion": "operator.victoriametrics.com/v1beta1",
+		"kind":       "VMUser",
+		"metadata":   map[string]any{"name": secretName, "namespace": storage.Namespace},
+		"spec": map[string]any{
+			"username": `{{ getField "` + childCredentialsIdentifier + `" "data.username" | b64dec }}`,
+			"passwordRef": map[string]any{
+				"name": secretName,
+				"key":  "password",
+			},
+			"targetRefs": []map[string]any{
+				{
+					"drop_src_path_prefix_parts": 1,
+					"paths":                      []string{"/vli/.*"},
+					// Overrides the tenant headers sent by the child.
+					"headers": logsHeaders,
+					"static":  map[string]any{"url": storage.VLInsertURL},
+				},
+				{
+					"drop_src_path_prefix_parts": 1,
+					"paths":                      []string{"/vm/select/" + metricsTenantID + "/.*"},
+					"static":                     map[string]any{"url": storage.VMSelectURL},
+				},
+				{
+					"drop_src_path_prefix_parts": 1,
+					"paths":                      []string{"/vm/insert/" + metricsTenantID + "/.*"},
+					"static":                     map[string]any{"url": storage.VMInsertURL},
+				},
+			},
+		},
+	}
+}
+
+// Deletes the objects created by `reconcileChildCredentials`, revoking the credentials
+// when the child opts out of them. Sveltos withdraws the deployed VMUser and Secrets with the Profiles.
+func (r *ClusterDeploymentReconciler) deleteChildCredentials(
+	ctx context.Context,
+	childClusterDeployment *kcmv1beta1.ClusterDeployment,
+) error {
+	for _, item := range childCredentialsObjects(childClusterDeployment) {
+		if err := r.deleteIfManaged(ctx, item.object, item.objectDescription); err != nil {
+			utils.LogEvent(
+				ctx,
+				"ChildObjectDeletionFailed",
+				"Failed to delete "+item.objectDescription+" of child cluster",
+				childClusterDeployment,
+				err,
+				"objectName", item.object.GetName(),
+				"objectNamespace", item.object.GetNamespace(),
+			)
+			return err
+		}
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package controller
 
-import "gopkg.in/yaml.v3"
+import (
+	kcmv1beta1 "github.com/K0rdent/kcm/api/v1beta1"
+	"gopkg.in/yaml.v3"
+)
 
 type ClusterDeploymentConfig struct {
 	ClusterAnnotations map[string]string `yaml:"clusterAnnotations"`
@@ -22,3 +25,11 @@ func ReadClusterDeploymentConfig(configYaml []byte) (*ClusterDeploymentConfig, e
 	}
 	return config, nil
 }
+
+// Returns the raw config of `clusterDeployment`, or nil if it is not set.
+func getConfigRaw(clusterDeployment *kcmv1beta1.ClusterDeployment) []byte {
+	if clusterDeployment.Spec.Config == nil {
+		return nil
+	}
+	return clusterDeployment.Spec.Config.Raw
+}
@@ -18,17 +18,28 @@ package controller
 
 import (
 	"context"
+	"os"
+	"slices"
 
 	kcmv1beta1 "github.com/K0rdent/kcm/api/v1beta1"
+	kofv1beta1 "github.com/k0rdent/kof/kof-operator/api/v1beta1"
 	"github.com/k0rdent/kof/kof-operator/internal/controller/istio/cert"
 	remotesecret "github.com/k0rdent/kof/kof-operator/internal/controller/istio/remote-secret"
 	"github.com/k0rdent/kof/kof-operator/internal/controller/utils"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 const IstioRoleLabel = "k0rdent.mirantis.com/istio-role"
@@ -39,10 +50,13 @@ type ClusterDeploymentReconciler struct {
 	Scheme              *runtime.Scheme
 	RemoteSecretManager *remotesecret.RemoteSecretManager
 	IstioCertManager    *cert.CertManager
+	// EndpointProber probes the endpoints of child clusters in the background, disabled if nil.
+	EndpointProber *EndpointProber
 }
 
 // +kubebuilder:rbac:groups=k0rdent.mirantis.com,resources=clusterdeployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=k0rdent.mirantis.com,resources=clusterdeployments/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=k0rdent.mirantis.com,resources=clusterdeployments/finalizers,verbs=update
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -85,20 +99,32 @@ func (r *ClusterDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Re
 				)
 				return ctrl.Result{}, err
 			}
-
 			return ctrl.Result{}, nil
 		}
 		log.Error(err, "cannot read clusterDeployment")
 		return ctrl.Result{}, err
 	}
 
-	if err := r.ReconcileKofClusterRole(ctx, clusterDeployment); err != nil {
+	reconcileErr := r.reconcileClusterDeployment(ctx, clusterDeployment, req)
+	if err := r.reconcileKofCluster(ctx, clusterDeployment, reconcileErr); err != nil && reconcileErr == nil {
 		return ctrl.Result{}, err
 	}
+	return ctrl.Result{}, reconcileErr
+}
+
+// Reconciles the kof cluster role and the Istio objects of the existing `clusterDeployment`.
+func (r *ClusterDeploymentReconciler) reconcileClusterDeployment(
+	ctx context.Context,
+	clusterDeployment *kcmv1beta1.ClusterDeployment,
+	req ctrl.Request,
+) error {
+	if err := r.ReconcileKofClusterRole(ctx, clusterDeployment); err != nil {
+		return err
+	}
 
 	if istioRole, ok := clusterDeployment.Labels[IstioRoleLabel]; ok {
 		if istioRole != "child" {
-			return ctrl.Result{}, nil
+			return nil
 		}
 
 		if err := r.RemoteSecretManager.TryCreate(clusterDeployment, ctx, req); err != nil {
@@ -110,7 +136,7 @@ func (r *ClusterDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Re
 				err,
 				"remoteSecretName", remotesecret.GetRemoteSecretName(req.Name),
 			)
-			return ctrl.Result{}, err
+			return err
 		}
 
 		if err := r.IstioCertManager.TryCreate(ctx, clusterDeployment); err != nil {
@@ -122,16 +148,177 @@ func (r *ClusterDeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Re
 				err,
 				"certName", cert.GetCertName(req.Name),
 			)
-			return ctrl.Result{}, err
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Enqueues child ClusterDeployments which may use the regional `obj`,
+// so their ConfigMaps are updated when the regional config or role changes.
+// Children without the regional cluster name label are enqueued too, as they are discovered by location,
+// and children writing to the regional `obj` as to an additional regional cluster.
+func (r *ClusterDeploymentReconciler) mapRegionalToChildClusterDeployments(
+	ctx context.Context,
+	obj client.Object,
+) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	if obj.GetLabels()[KofClusterRoleLabel] != "regional" &&
+		obj.GetAnnotations()[KofAppliedClusterRoleAnnotation] != "regional" {
+		return nil
+	}
+
+	childClusterDeploymentsList := &kcmv1beta1.ClusterDeploymentList{}
+	if err := r.List(
+		ctx,
+		childClusterDeploymentsList,
+		client.InNamespace(obj.GetNamespace()),
+		client.MatchingLabels{KofClusterRoleLabel: "child"},
+	); err != nil {
+		log.Error(err, "cannot list child ClusterDeployments")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, childClusterDeployment := range childClusterDeploymentsList.Items {
+		regionalClusterName, ok := childClusterDeployment.Labels[KofRegionalClusterNameLabel]
+		if ok && regionalClusterName != obj.GetName() &&
+			!slices.Contains(getAdditionalRegionalClusterNames(&childClusterDeployment), obj.GetName()) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      childClusterDeployment.Name,
+				Namespace: childClusterDeployment.Namespace,
+			},
+		})
+	}
+	return requests
+}
+
+// Enqueues child ClusterDeployments without the regional cluster name label,
+// so they are discovered again when the regional cluster mapping ConfigMap changes.
+func (r *ClusterDeploymentReconciler) mapRegionalClusterMappingToChildClusterDeployments(
+	ctx context.Context,
+	obj client.Object,
+) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	childClusterDeploymentsList := &kcmv1beta1.ClusterDeploymentList{}
+	if err := r.List(
+		ctx,
+		childClusterDeploymentsList,
+		client.MatchingLabels{KofClusterRoleLabel: "child"},
+	); err != nil {
+		log.Error(err, "cannot list child ClusterDeployments")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, childClusterDeployment := range childClusterDeploymentsList.Items {
+		if _, ok := childClusterDeployment.Labels[KofRegionalClusterNameLabel]; ok {
+			continue
 		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      childClusterDeployment.Name,
+				Namespace: childClusterDeployment.Namespace,
+			},
+		})
+	}
+	return requests
+}
+
+// Enqueues ClusterDeployments with kof cluster role, so their endpoints and the objects of tenants
+// are updated when the tenant mapping or the endpoint templates ConfigMap changes.
+func (r *ClusterDeploymentReconciler) mapKofConfigMapToClusterDeployments(
+	ctx context.Context,
+	obj client.Object,
+) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	clusterDeploymentsList := &kcmv1beta1.ClusterDeploymentList{}
+	if err := r.List(
+		ctx,
+		clusterDeploymentsList,
+		client.HasLabels{KofClusterRoleLabel},
+	); err != nil {
+		log.Error(err, "cannot list ClusterDeployments with kof cluster role")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(clusterDeploymentsList.Items))
+	for _, clusterDeployment := range clusterDeploymentsList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      clusterDeployment.Name,
+				Namespace: clusterDeployment.Namespace,
+			},
+		})
+	}
+	return requests
+}
+
+// Passes the updates of ClusterDeployments changing the status of their Ready condition.
+func readyConditionChangedPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldClusterDeployment, ok := e.ObjectOld.(*kcmv1beta1.ClusterDeployment)
+			if !ok {
+				return false
+			}
+			newClusterDeployment, ok := e.ObjectNew.(*kcmv1beta1.ClusterDeployment)
+			if !ok {
+				return false
+			}
+			return getReadyConditionStatus(oldClusterDeployment) != getReadyConditionStatus(newClusterDeployment)
+		},
 	}
+}
 
-	return ctrl.Result{}, nil
+func getReadyConditionStatus(clusterDeployment *kcmv1beta1.ClusterDeployment) metav1.ConditionStatus {
+	readyCondition := meta.FindStatusCondition(clusterDeployment.Status.Conditions, kcmv1beta1.ReadyCondition)
+	if readyCondition == nil {
+		return ""
+	}
+	return readyCondition.Status
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ClusterDeploymentReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&kcmv1beta1.ClusterDeployment{}).
+		Owns(&kofv1beta1.KofCluster{}).
+		// Child cluster ConfigMaps are derived from the config of the regional cluster.
+		// Status updates of the regional cluster are frequent, only the Ready transitions matter to children.
+		Watches(
+			&kcmv1beta1.ClusterDeployment{},
+			handler.EnqueueRequestsFromMapFunc(r.mapRegionalToChildClusterDeployments),
+			builder.WithPredicates(predicate.Or(
+				predicate.GenerationChangedPredicate{},
+				predicate.LabelChangedPredicate{},
+				predicate.AnnotationChangedPredicate{},
+				readyConditionChangedPredicate(),
+			)),
+		).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.mapRegionalClusterMappingToChildClusterDeployments),
+			builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+				return obj.GetName() == KofRegionalClusterMappingConfigMapName &&
+					obj.GetNamespace() == os.Getenv("RELEASE_NAMESPACE")
+			})),
+		).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.mapKofConfigMapToClusterDeployments),
+			builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+				return (obj.GetName() == KofTenantMappingConfigMapName ||
+					obj.GetName() == KofEndpointTemplatesConfigMapName) &&
+					obj.GetNamespace() == os.Getenv("RELEASE_NAMESPACE")
+			})),
+		).
 		Complete(r)
 }
@@ -20,6 +20,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"time"
 
 	kcmv1beta1 "github.com/K0rdent/kcm/api/v1beta1"
@@ -35,8 +38,11 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -176,6 +182,22 @@ var _ = Describe("ClusterDeployment Controller", func() {
 			Expect(k8sClient.Create(ctx, kubeconfigSecret)).To(Succeed())
 		}
 
+		// deleteClusterDeployment
+
+		deleteClusterDeployment := func(namespacedName types.NamespacedName) {
+			clusterDeployment := &kcmv1beta1.ClusterDeployment{}
+			if err := k8sClient.Get(ctx, namespacedName, clusterDeployment); err != nil {
+				return
+			}
+			Expect(k8sClient.Delete(ctx, clusterDeployment)).To(Succeed())
+
+			// Regional ClusterDeployment is deleted after the reconciler removes its finalizer.
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: namespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			err = k8sClient.Get(ctx, namespacedName, clusterDeployment)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		}
+
 		// before each test case
 
 		BeforeEach(func() {
@@ -223,17 +245,11 @@ var _ = Describe("ClusterDeployment Controller", func() {
 		// after each test case
 
 		AfterEach(func() {
-			cd := &kcmv1beta1.ClusterDeployment{}
-
-			if err := k8sClient.Get(ctx, regionalClusterDeploymentNamespacedName, cd); err == nil {
-				By("Cleanup regional ClusterDeployment")
-				Expect(k8sClient.Delete(ctx, cd)).To(Succeed())
-			}
+			By("Cleanup regional ClusterDeployment")
+			deleteClusterDeployment(regionalClusterDeploymentNamespacedName)
 
-			if err := k8sClient.Get(ctx, childClusterDeploymentNamespacedName, cd); err == nil {
-				By("Cleanup child ClusterDeployment")
-				Expect(k8sClient.Delete(ctx, cd)).To(Succeed())
-			}
+			By("Cleanup child ClusterDeployment")
+			deleteClusterDeployment(childClusterDeploymentNamespacedName)
 
 			configMap := &corev1.ConfigMap{}
 			if err := k8sClient.Get(ctx, childClusterConfigMapNamespacedName, configMap); err == nil {
@@ -258,6 +274,18 @@ var _ = Describe("ClusterDeployment Controller", func() {
 				By("Cleanup the Certificate")
 				Expect(k8sClient.Delete(ctx, cert)).To(Succeed())
 			}
+
+			// KofClusters are deleted by the garbage collector, which does not run in envtest.
+			for _, namespacedName := range []types.NamespacedName{
+				regionalClusterDeploymentNamespacedName,
+				childClusterDeploymentNamespacedName,
+			} {
+				kofCluster := &kofv1beta1.KofCluster{}
+				if err := k8sClient.Get(ctx, namespacedName, kofCluster); err == nil {
+					By("Cleanup KofCluster " + namespacedName.Name)
+					Expect(k8sClient.Delete(ctx, kofCluster)).To(Succeed())
+				}
+			}
 		})
 
 		// test cases
@@ -457,11 +485,8 @@ var _ = Describe("ClusterDeployment Controller", func() {
 			)
 
 			DeferCleanup(func() {
-				regionalClusterDeployment := &kcmv1beta1.ClusterDeployment{}
-				if err := k8sClient.Get(ctx, regionalClusterDeploymentNamespacedName, regionalClusterDeployment); err == nil {
-					By("cleanup regional ClusterDeployment")
-					Expect(k8sClient.Delete(ctx, regionalClusterDeployment)).To(Succeed())
-				}
+				By("cleanup regional ClusterDeployment")
+				deleteClusterDeployment(regionalClusterDeploymentNamespacedName)
 
 				kubeconfigSecretNamespacedName := types.NamespacedName{
 					Name:      secretName,
@@ -598,6 +623,91 @@ var _ = Describe("ClusterDeployment Controller", func() {
 				},
 				"https://vmauth.custom.example.com/vls", `{"tlsSkipVerify": true, "timeout": "10"}`,
 			),
+
+			Entry(
+				"Custom endpoints with TLS config",
+				map[string]string{KofClusterRoleLabel: "regional"},
+				map[string]string{KofRegionalHTTPClientConfigAnnotation: `{"tls_config": {
+					"server_name": "vmauth.custom.example.com",
+					"ca": {"config_map": {"name": "regional-ca", "key": "ca.crt"}},
+					"cert": {"secret": {"name": "regional-tls", "key": "tls.crt"}},
+					"key_secret": {"name": "regional-tls", "key": "tls.key"}
+				}}`},
+				fmt.Sprintf(`{
+					"region": "us-east-2",
+					"clusterAnnotations": {"%s": "%s"}
+				}`,
+					KofRegionalDomainAnnotation, "custom.example.com",
+				),
+				"https",
+				"vmauth.custom.example.com:443",
+				"/vm/select/0/prometheus",
+				kofv1beta1.HTTPClientConfig{
+					DialTimeout: defaultDialTimeout,
+					TLSConfig: kofv1beta1.TLSConfig{
+						ServerName: "vmauth.custom.example.com",
+						CA: &kofv1beta1.SecretOrConfigMap{
+							ConfigMap: &corev1.ConfigMapKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "regional-ca"},
+								Key:                  "ca.crt",
+							},
+						},
+						Cert: &kofv1beta1.SecretOrConfigMap{
+							Secret: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "regional-tls"},
+								Key:                  "tls.crt",
+							},
+						},
+						KeySecret: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "regional-tls"},
+							Key:                  "tls.key",
+						},
+					},
+					BasicAuth: kofv1beta1.BasicAuth{
+						CredentialsSecretName: "storage-vmuser-credentials",
+						UsernameKey:           "username",
+						PasswordKey:           "password",
+					},
+				},
+				"https://vmauth.custom.example.com/vls", `{
+					"tlsSkipVerify": false,
+					"timeout": "5",
+					"serverName": "vmauth.custom.example.com",
+					"tlsAuthWithCACert": true,
+					"tlsAuth": true
+				}`,
+			),
+
+			Entry(
+				"Custom endpoints with bearer token",
+				map[string]string{KofClusterRoleLabel: "regional"},
+				map[string]string{KofRegionalHTTPClientConfigAnnotation: `{"bearer_token": {
+					"secret": {"name": "regional-token", "key": "token"}
+				}}`},
+				fmt.Sprintf(`{
+					"region": "us-east-2",
+					"clusterAnnotations": {"%s": "%s"}
+				}`,
+					KofRegionalDomainAnnotation, "custom.example.com",
+				),
+				"https",
+				"vmauth.custom.example.com:443",
+				"/vm/select/0/prometheus",
+				kofv1beta1.HTTPClientConfig{
+					DialTimeout: defaultDialTimeout,
+					BearerToken: &kofv1beta1.BearerToken{
+						Secret: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "regional-token"},
+							Key:                  "token",
+						},
+					},
+				},
+				"https://vmauth.custom.example.com/vls", `{
+					"tlsSkipVerify": false,
+					"timeout": "5",
+					"httpHeaderName1": "Authorization"
+				}`,
+			),
 		)
 
 		It("should create ConfigMap for child cluster", func() {
@@ -626,77 +736,1189 @@ var _ = Describe("ClusterDeployment Controller", func() {
 			))
 		})
 
-		It("should discover regional cluster by AWS region", func() {
-			By("creating child ClusterDeployment without kof-regional-cluster-name label")
-			const childClusterDeploymentName = "test-child-aws"
+		It("should summarise regional and child clusters in KofClusters", func() {
+			By("reconciling regional and child ClusterDeployments")
+			for _, namespacedName := range []types.NamespacedName{
+				regionalClusterDeploymentNamespacedName,
+				childClusterDeploymentNamespacedName,
+			} {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: namespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
 
-			childClusterDeploymentNamespacedName := types.NamespacedName{
-				Name:      childClusterDeploymentName,
-				Namespace: defaultNamespace,
+			By("reading regional KofCluster")
+			regionalKofCluster := &kofv1beta1.KofCluster{}
+			Expect(k8sClient.Get(ctx, regionalClusterDeploymentNamespacedName, regionalKofCluster)).To(Succeed())
+			Expect(regionalKofCluster.OwnerReferences).To(ContainElement(HaveField("Name", regionalClusterDeploymentName)))
+			Expect(regionalKofCluster.Status.Role).To(Equal("regional"))
+			Expect(regionalKofCluster.Status.RegionalClusterName).To(BeEmpty())
+			Expect(regionalKofCluster.Status.Istio).To(BeNil())
+			Expect(regionalKofCluster.Status.Endpoints).To(Equal(kofv1beta1.KofClusterEndpoints{
+				ReadMetrics:  "https://vmauth.test-aws-ue2.kof.example.com/vm/select/0/prometheus",
+				WriteMetrics: "https://vmauth.test-aws-ue2.kof.example.com/vm/insert/0/prometheus/api/v1/write",
+				ReadLogs:     "https://vmauth.test-aws-ue2.kof.example.com/vls",
+				WriteLogs:    "https://vmauth.test-aws-ue2.kof.example.com/vli/insert/opentelemetry/v1/logs",
+				WriteTraces:  "https://jaeger.test-aws-ue2.kof.example.com/collector",
+				ReadTraces:   "https://jaeger.test-aws-ue2.kof.example.com",
+			}))
+			Expect(meta.IsStatusConditionTrue(
+				regionalKofCluster.Status.Conditions, kofv1beta1.KofClusterReconciledCondition,
+			)).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(
+				regionalKofCluster.Status.Conditions, kofv1beta1.KofClusterReadyCondition,
+			)).To(BeTrue())
+			Expect(meta.FindStatusCondition(
+				regionalKofCluster.Status.Conditions, kofv1beta1.KofRegionalClusterReadyCondition,
+			)).To(BeNil())
+
+			By("reading child KofCluster")
+			childKofCluster := &kofv1beta1.KofCluster{}
+			Expect(k8sClient.Get(ctx, childClusterDeploymentNamespacedName, childKofCluster)).To(Succeed())
+			Expect(childKofCluster.Status.Role).To(Equal("child"))
+			Expect(childKofCluster.Status.RegionalClusterName).To(Equal(regionalClusterDeploymentName))
+			Expect(childKofCluster.Status.Endpoints).To(Equal(kofv1beta1.KofClusterEndpoints{
+				ReadMetrics:  "https://vmauth.test-aws-ue2.kof.example.com/vm/select/0/prometheus",
+				WriteMetrics: "https://vmauth.test-aws-ue2.kof.example.com/vm/insert/0/prometheus/api/v1/write",
+				WriteLogs:    "https://vmauth.test-aws-ue2.kof.example.com/vli/insert/opentelemetry/v1/logs",
+				WriteTraces:  "https://jaeger.test-aws-ue2.kof.example.com/collector",
+			}))
+			Expect(childKofCluster.Status.Istio).To(Equal(&kofv1beta1.KofClusterIstioStatus{
+				Role:             "child",
+				RemoteSecretName: remoteSecretNamespacedName.Name,
+			}))
+			Expect(meta.IsStatusConditionTrue(
+				childKofCluster.Status.Conditions, kofv1beta1.KofRegionalClusterReadyCondition,
+			)).To(BeTrue())
+
+			By("reconciling child ClusterDeployment again without status changes")
+			resourceVersion := childKofCluster.ResourceVersion
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, childClusterDeploymentNamespacedName, childKofCluster)).To(Succeed())
+			Expect(childKofCluster.ResourceVersion).To(Equal(resourceVersion))
+
+			By("reporting failed reconciliation when regional cluster is deleted")
+			deleteClusterDeployment(regionalClusterDeploymentNamespacedName)
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(k8sClient.Get(ctx, childClusterDeploymentNamespacedName, childKofCluster)).To(Succeed())
+			reconciledCondition := meta.FindStatusCondition(
+				childKofCluster.Status.Conditions, kofv1beta1.KofClusterReconciledCondition,
+			)
+			Expect(reconciledCondition).NotTo(BeNil())
+			Expect(reconciledCondition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(reconciledCondition.Message).To(ContainSubstring("not found"))
+			Expect(meta.FindStatusCondition(
+				childKofCluster.Status.Conditions, kofv1beta1.KofRegionalClusterReadyCondition,
+			)).To(HaveField("Reason", "RegionalClusterNotFound"))
+
+			By("deleting KofCluster when kof and istio role labels are removed")
+			childClusterDeployment := &kcmv1beta1.ClusterDeployment{}
+			Expect(k8sClient.Get(ctx, childClusterDeploymentNamespacedName, childClusterDeployment)).To(Succeed())
+			delete(childClusterDeployment.Labels, KofClusterRoleLabel)
+			delete(childClusterDeployment.Labels, IstioRoleLabel)
+			delete(childClusterDeployment.Labels, KofRegionalClusterNameLabel)
+			Expect(k8sClient.Update(ctx, childClusterDeployment)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			err = k8sClient.Get(ctx, childClusterDeploymentNamespacedName, childKofCluster)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should update generated objects when regional config changes", func() {
+			promxyServerGroupNamespacedName := types.NamespacedName{
+				Name:      regionalClusterDeploymentName + "-metrics",
+				Namespace: ReleaseNamespace,
 			}
 
-			childClusterConfigMapNamespacedName := types.NamespacedName{
-				Name:      "kof-cluster-config-" + childClusterDeploymentName,
-				Namespace: defaultNamespace,
+			grafanaDatasourceNamespacedName := types.NamespacedName{
+				Name:      regionalClusterDeploymentName + "-logs",
+				Namespace: ReleaseNamespace,
 			}
 
-			childClusterDeploymentLabels := map[string]string{
-				KofClusterRoleLabel: "child",
-				// Note no `KofRegionalClusterNameLabel` here, it will be auto-discovered!
+			DeferCleanup(func() {
+				promxyServerGroup := &kofv1beta1.PromxyServerGroup{}
+				if err := k8sClient.Get(ctx, promxyServerGroupNamespacedName, promxyServerGroup); err == nil {
+					By("cleanup PromxyServerGroup")
+					Expect(k8sClient.Delete(ctx, promxyServerGroup)).To(Succeed())
+				}
+
+				grafanaDatasource := &grafanav1beta1.GrafanaDatasource{}
+				if err := k8sClient.Get(ctx, grafanaDatasourceNamespacedName, grafanaDatasource); err == nil {
+					By("cleanup GrafanaDatasource")
+					Expect(k8sClient.Delete(ctx, grafanaDatasource)).To(Succeed())
+				}
+			})
+
+			reconcileAll := func() {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: regionalClusterDeploymentNamespacedName,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: childClusterDeploymentNamespacedName,
+				})
+				Expect(err).NotTo(HaveOccurred())
 			}
 
-			childClusterDeploymentAnnotations := map[string]string{}
+			By("reconciling regional and child ClusterDeployments")
+			reconcileAll()
+
+			By("changing regional domain and http config")
+			regionalClusterDeployment := &kcmv1beta1.ClusterDeployment{}
+			Expect(k8sClient.Get(ctx, regionalClusterDeploymentNamespacedName, regionalClusterDeployment)).To(Succeed())
+			regionalClusterDeployment.Spec.Config = &apiextensionsv1.JSON{Raw: []byte(fmt.Sprintf(`{
+				"region": "us-east-2",
+				"clusterAnnotations": {"%s": "%s"}
+			}`, KofRegionalDomainAnnotation, "test-aws-ue2-new.kof.example.com"))}
+			regionalClusterDeployment.Annotations = map[string]string{
+				KofRegionalHTTPClientConfigAnnotation: `{"dial_timeout": "10s"}`,
+			}
+			Expect(k8sClient.Update(ctx, regionalClusterDeployment)).To(Succeed())
 
-			createClusterDeployment(
-				childClusterDeploymentName,
-				childClusterDeploymentLabels,
-				childClusterDeploymentAnnotations,
-				childClusterDeploymentConfig,
+			By("enqueuing child ClusterDeployment of the regional cluster")
+			Expect(controllerReconciler.mapRegionalToChildClusterDeployments(ctx, regionalClusterDeployment)).To(
+				ContainElement(reconcile.Request{NamespacedName: childClusterDeploymentNamespacedName}),
 			)
 
-			DeferCleanup(func() {
-				childClusterDeployment := &kcmv1beta1.ClusterDeployment{}
-				if err := k8sClient.Get(ctx, childClusterDeploymentNamespacedName, childClusterDeployment); err == nil {
-					By("cleanup child ClusterDeployment")
-					Expect(k8sClient.Delete(ctx, childClusterDeployment)).To(Succeed())
-				}
+			By("reconciling regional and child ClusterDeployments again")
+			reconcileAll()
 
-				configMap := &corev1.ConfigMap{}
-				if err := k8sClient.Get(ctx, childClusterConfigMapNamespacedName, configMap); err == nil {
-					By("cleanup child cluster ConfigMap")
-					Expect(k8sClient.Delete(ctx, configMap)).To(Succeed())
-				}
+			By("reading updated PromxyServerGroup")
+			promxyServerGroup := &kofv1beta1.PromxyServerGroup{}
+			Expect(k8sClient.Get(ctx, promxyServerGroupNamespacedName, promxyServerGroup)).To(Succeed())
+			Expect(promxyServerGroup.Spec.Targets).To(Equal([]string{"vmauth.test-aws-ue2-new.kof.example.com:443"}))
+			Expect(promxyServerGroup.Spec.HttpClient.DialTimeout.Duration).To(Equal(10 * time.Second))
+
+			By("reading updated GrafanaDatasource")
+			grafanaDatasource := &grafanav1beta1.GrafanaDatasource{}
+			Expect(k8sClient.Get(ctx, grafanaDatasourceNamespacedName, grafanaDatasource)).To(Succeed())
+			Expect(grafanaDatasource.Spec.Datasource.URL).To(Equal("https://vmauth.test-aws-ue2-new.kof.example.com/vls"))
+			Expect(grafanaDatasource.Spec.Datasource.JSONData).To(MatchJSON(`{"tlsSkipVerify": false, "timeout": "10"}`))
+
+			By("reading updated child cluster ConfigMap")
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, childClusterConfigMapNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data[ReadMetricsKey]).To(Equal(
+				"https://vmauth.test-aws-ue2-new.kof.example.com/vm/select/0/prometheus",
+			))
+
+			By("reconciling unchanged ClusterDeployments without updates")
+			reconcileAll()
+			unchangedPromxyServerGroup := &kofv1beta1.PromxyServerGroup{}
+			Expect(k8sClient.Get(ctx, promxyServerGroupNamespacedName, unchangedPromxyServerGroup)).To(Succeed())
+			Expect(unchangedPromxyServerGroup.ResourceVersion).To(Equal(promxyServerGroup.ResourceVersion))
+			unchangedGrafanaDatasource := &grafanav1beta1.GrafanaDatasource{}
+			Expect(k8sClient.Get(ctx, grafanaDatasourceNamespacedName, unchangedGrafanaDatasource)).To(Succeed())
+			Expect(unchangedGrafanaDatasource.ResourceVersion).To(Equal(grafanaDatasource.ResourceVersion))
+		})
+
+		It("should delete regional objects when regional cluster is deleted", func() {
+			promxyServerGroupNamespacedName := types.NamespacedName{
+				Name:      regionalClusterDeploymentName + "-metrics",
+				Namespace: ReleaseNamespace,
+			}
+
+			grafanaDatasourceNamespacedName := types.NamespacedName{
+				Name:      regionalClusterDeploymentName + "-logs",
+				Namespace: ReleaseNamespace,
+			}
+
+			vmRulesConfigMapNamespacedName := types.NamespacedName{
+				Name:      "kof-record-vmrules-" + regionalClusterDeploymentName,
+				Namespace: defaultNamespace,
+			}
+
+			By("reconciling regional ClusterDeployment")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: regionalClusterDeploymentNamespacedName,
 			})
+			Expect(err).NotTo(HaveOccurred())
 
-			By("reconciling child ClusterDeployment")
+			regionalClusterDeployment := &kcmv1beta1.ClusterDeployment{}
+			Expect(k8sClient.Get(ctx, regionalClusterDeploymentNamespacedName, regionalClusterDeployment)).To(Succeed())
+			Expect(regionalClusterDeployment.Finalizers).To(ContainElement(KofRegionalClusterFinalizer))
+			Expect(k8sClient.Get(ctx, promxyServerGroupNamespacedName, &kofv1beta1.PromxyServerGroup{})).To(Succeed())
+			Expect(k8sClient.Get(ctx, grafanaDatasourceNamespacedName, &grafanav1beta1.GrafanaDatasource{})).To(Succeed())
+			Expect(k8sClient.Get(ctx, vmRulesConfigMapNamespacedName, &corev1.ConfigMap{})).To(Succeed())
+
+			By("deleting regional ClusterDeployment")
+			deleteClusterDeployment(regionalClusterDeploymentNamespacedName)
+
+			By("checking regional objects are deleted")
+			err = k8sClient.Get(ctx, promxyServerGroupNamespacedName, &kofv1beta1.PromxyServerGroup{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			err = k8sClient.Get(ctx, grafanaDatasourceNamespacedName, &grafanav1beta1.GrafanaDatasource{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			err = k8sClient.Get(ctx, vmRulesConfigMapNamespacedName, &corev1.ConfigMap{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should delete regional objects when regional role label is removed", func() {
+			By("reconciling regional ClusterDeployment")
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
-				NamespacedName: childClusterDeploymentNamespacedName,
+				NamespacedName: regionalClusterDeploymentNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
 
-			By("reading created ConfigMap")
-			configMap := &corev1.ConfigMap{}
-			err = k8sClient.Get(ctx, childClusterConfigMapNamespacedName, configMap)
+			regionalClusterDeployment := &kcmv1beta1.ClusterDeployment{}
+			Expect(k8sClient.Get(ctx, regionalClusterDeploymentNamespacedName, regionalClusterDeployment)).To(Succeed())
+			Expect(regionalClusterDeployment.Annotations).To(HaveKeyWithValue(KofAppliedClusterRoleAnnotation, "regional"))
+
+			By("removing regional role label")
+			delete(regionalClusterDeployment.Labels, KofClusterRoleLabel)
+			Expect(k8sClient.Update(ctx, regionalClusterDeployment)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: regionalClusterDeploymentNamespacedName,
+			})
 			Expect(err).NotTo(HaveOccurred())
-			Expect(configMap.Data[RegionalClusterNameKey]).To(Equal("test-regional"))
+
+			By("checking regional objects, finalizer and annotation are deleted")
+			Expect(k8sClient.Get(ctx, regionalClusterDeploymentNamespacedName, regionalClusterDeployment)).To(Succeed())
+			Expect(regionalClusterDeployment.Finalizers).NotTo(ContainElement(KofRegionalClusterFinalizer))
+			Expect(regionalClusterDeployment.Annotations).NotTo(HaveKey(KofAppliedClusterRoleAnnotation))
+			err = k8sClient.Get(ctx, types.NamespacedName{
+				Name:      regionalClusterDeploymentName + "-metrics",
+				Namespace: ReleaseNamespace,
+			}, &kofv1beta1.PromxyServerGroup{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			err = k8sClient.Get(ctx, types.NamespacedName{
+				Name:      regionalClusterDeploymentName + "-logs",
+				Namespace: ReleaseNamespace,
+			}, &grafanav1beta1.GrafanaDatasource{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
 		})
 
-		It("should create profile", func() {
-			By("reading child ClusterDeployment")
+		It("should create optional metrics and traces GrafanaDatasources for regional cluster", func() {
+			metricsGrafanaDatasourceNamespacedName := types.NamespacedName{
+				Name:      regionalClusterDeploymentName + "-metrics",
+				Namespace: ReleaseNamespace,
+			}
+			tracesGrafanaDatasourceNamespacedName := types.NamespacedName{
+				Name:      regionalClusterDeploymentName + "-traces",
+				Namespace: ReleaseNamespace,
+			}
+
+			By("enabling optional GrafanaDatasources")
+			regionalClusterDeployment := &kcmv1beta1.ClusterDeployment{}
+			Expect(k8sClient.Get(ctx, regionalClusterDeploymentNamespacedName, regionalClusterDeployment)).To(Succeed())
+			metav1.SetMetaDataAnnotation(&regionalClusterDeployment.ObjectMeta, KofGrafanaDatasourcesAnnotation, "metrics, traces")
+			Expect(k8sClient.Update(ctx, regionalClusterDeployment)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: regionalClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("reading metrics GrafanaDatasource")
+			grafanaDatasource := &grafanav1beta1.GrafanaDatasource{}
+			Expect(k8sClient.Get(ctx, metricsGrafanaDatasourceNamespacedName, grafanaDatasource)).To(Succeed())
+			Expect(grafanaDatasource.Spec.Datasource.Type).To(Equal("prometheus"))
+			Expect(grafanaDatasource.Spec.Datasource.URL).To(Equal(
+				"https://vmauth.test-aws-ue2.kof.example.com/vm/select/0/prometheus",
+			))
+			Expect(*grafanaDatasource.Spec.Datasource.BasicAuth).To(BeTrue())
+
+			By("reading traces GrafanaDatasource")
+			Expect(k8sClient.Get(ctx, tracesGrafanaDatasourceNamespacedName, grafanaDatasource)).To(Succeed())
+			Expect(grafanaDatasource.Spec.Datasource.Type).To(Equal("jaeger"))
+			Expect(grafanaDatasource.Spec.Datasource.URL).To(Equal("https://jaeger.test-aws-ue2.kof.example.com"))
+
+			By("disabling traces GrafanaDatasource")
+			Expect(k8sClient.Get(ctx, regionalClusterDeploymentNamespacedName, regionalClusterDeployment)).To(Succeed())
+			metav1.SetMetaDataAnnotation(&regionalClusterDeployment.ObjectMeta, KofGrafanaDatasourcesAnnotation, "metrics")
+			Expect(k8sClient.Update(ctx, regionalClusterDeployment)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: regionalClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, metricsGrafanaDatasourceNamespacedName, grafanaDatasource)).To(Succeed())
+			err = k8sClient.Get(ctx, tracesGrafanaDatasourceNamespacedName, &grafanav1beta1.GrafanaDatasource{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			By("deleting regional cluster")
+			deleteClusterDeployment(regionalClusterDeploymentNamespacedName)
+			err = k8sClient.Get(ctx, metricsGrafanaDatasourceNamespacedName, &grafanav1beta1.GrafanaDatasource{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should replace child objects when child cluster becomes regional", func() {
+			By("reconciling child ClusterDeployment")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, childClusterConfigMapNamespacedName, &corev1.ConfigMap{})).To(Succeed())
+			Expect(k8sClient.Get(ctx, profileDeploymentName, &sveltosv1beta1.Profile{})).To(Succeed())
+
+			By("changing child role to regional")
 			clusterDeployment := &kcmv1beta1.ClusterDeployment{}
-			err := k8sClient.Get(ctx, childClusterDeploymentNamespacedName, clusterDeployment)
+			Expect(k8sClient.Get(ctx, childClusterDeploymentNamespacedName, clusterDeployment)).To(Succeed())
+			clusterDeployment.Labels[KofClusterRoleLabel] = "regional"
+			delete(clusterDeployment.Labels, KofRegionalClusterNameLabel)
+			Expect(k8sClient.Update(ctx, clusterDeployment)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
 			Expect(err).NotTo(HaveOccurred())
 
+			By("checking child objects are deleted and regional objects are created")
+			err = k8sClient.Get(ctx, childClusterConfigMapNamespacedName, &corev1.ConfigMap{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			err = k8sClient.Get(ctx, profileDeploymentName, &sveltosv1beta1.Profile{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      childClusterDeploymentName + "-metrics",
+				Namespace: ReleaseNamespace,
+			}, &kofv1beta1.PromxyServerGroup{})).To(Succeed())
+
+			Expect(k8sClient.Get(ctx, childClusterDeploymentNamespacedName, clusterDeployment)).To(Succeed())
+			Expect(clusterDeployment.Annotations).To(HaveKeyWithValue(KofAppliedClusterRoleAnnotation, "regional"))
+		})
+
+		It("should update child objects when regional cluster name label is changed", func() {
+			const otherRegionalClusterDeploymentName = "test-regional-other"
+
+			otherRegionalClusterDeploymentNamespacedName := types.NamespacedName{
+				Name:      otherRegionalClusterDeploymentName,
+				Namespace: defaultNamespace,
+			}
+
+			createClusterDeployment(
+				otherRegionalClusterDeploymentName,
+				regionalClusterDeploymentLabels,
+				map[string]string{},
+				fmt.Sprintf(`{
+					"region": "us-west-2",
+					"clusterAnnotations": {"%s": "%s"}
+				}`, KofRegionalDomainAnnotation, "test-aws-uw2.kof.example.com"),
+			)
+
+			DeferCleanup(func() {
+				By("cleanup other regional ClusterDeployment")
+				deleteClusterDeployment(otherRegionalClusterDeploymentNamespacedName)
+			})
+
 			By("reconciling child ClusterDeployment")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("changing regional cluster name label")
+			clusterDeployment := &kcmv1beta1.ClusterDeployment{}
+			Expect(k8sClient.Get(ctx, childClusterDeploymentNamespacedName, clusterDeployment)).To(Succeed())
+			clusterDeployment.Labels[KofRegionalClusterNameLabel] = otherRegionalClusterDeploymentName
+			Expect(k8sClient.Update(ctx, clusterDeployment)).To(Succeed())
+
 			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: childClusterDeploymentNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
 
-			By("reading profile")
+			By("reading updated ConfigMap and Profile")
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, childClusterConfigMapNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data[RegionalClusterNameKey]).To(Equal(otherRegionalClusterDeploymentName))
+			Expect(configMap.Data[WriteMetricsKey]).To(Equal(
+				"https://vmauth.test-aws-uw2.kof.example.com/vm/insert/0/prometheus/api/v1/write",
+			))
+
 			profile := &sveltosv1beta1.Profile{}
-			err = k8sClient.Get(ctx, profileDeploymentName, profile)
+			Expect(k8sClient.Get(ctx, profileDeploymentName, profile)).To(Succeed())
+			Expect(profile.Spec.TemplateResourceRefs[0].Resource.Name).To(Equal(
+				remotesecret.GetRemoteSecretName(otherRegionalClusterDeploymentName),
+			))
+		})
+
+		It("should not update ConfigMap of child cluster created by user", func() {
+			By("creating ConfigMap without managed-by label")
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      childClusterConfigMapNamespacedName.Name,
+					Namespace: childClusterConfigMapNamespacedName.Namespace,
+				},
+				Data: map[string]string{RegionalClusterNameKey: "custom-regional"},
+			}
+			Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+
+			By("reconciling child ClusterDeployment")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("reading unchanged ConfigMap")
+			Expect(k8sClient.Get(ctx, childClusterConfigMapNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data).To(Equal(map[string]string{RegionalClusterNameKey: "custom-regional"}))
+		})
+
+		It("should discover regional cluster by AWS region", func() {
+			By("creating child ClusterDeployment without kof-regional-cluster-name label")
+			const childClusterDeploymentName = "test-child-aws"
+
+			childClusterDeploymentNamespacedName := types.NamespacedName{
+				Name:      childClusterDeploymentName,
+				Namespace: defaultNamespace,
+			}
+
+			childClusterConfigMapNamespacedName := types.NamespacedName{
+				Name:      "kof-cluster-config-" + childClusterDeploymentName,
+				Namespace: defaultNamespace,
+			}
+
+			childClusterDeploymentLabels := map[string]string{
+				KofClusterRoleLabel: "child",
+				// Note no `KofRegionalClusterNameLabel` here, it will be auto-discovered!
+			}
+
+			childClusterDeploymentAnnotations := map[string]string{}
+
+			createClusterDeployment(
+				childClusterDeploymentName,
+				childClusterDeploymentLabels,
+				childClusterDeploymentAnnotations,
+				childClusterDeploymentConfig,
+			)
+
+			DeferCleanup(func() {
+				childClusterDeployment := &kcmv1beta1.ClusterDeployment{}
+				if err := k8sClient.Get(ctx, childClusterDeploymentNamespacedName, childClusterDeployment); err == nil {
+					By("cleanup child ClusterDeployment")
+					Expect(k8sClient.Delete(ctx, childClusterDeployment)).To(Succeed())
+				}
+
+				configMap := &corev1.ConfigMap{}
+				if err := k8sClient.Get(ctx, childClusterConfigMapNamespacedName, configMap); err == nil {
+					By("cleanup child cluster ConfigMap")
+					Expect(k8sClient.Delete(ctx, configMap)).To(Succeed())
+				}
+			})
+
+			By("reconciling child ClusterDeployment")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("reading created ConfigMap")
+			configMap := &corev1.ConfigMap{}
+			err = k8sClient.Get(ctx, childClusterConfigMapNamespacedName, configMap)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(configMap.Data[RegionalClusterNameKey]).To(Equal("test-regional"))
+		})
+
+		It("should discover regional cluster of adopted child by mapping", func() {
+			const adoptedChildClusterDeploymentName = "test-child-adopted"
+
+			adoptedChildClusterDeploymentNamespacedName := types.NamespacedName{
+				Name:      adoptedChildClusterDeploymentName,
+				Namespace: defaultNamespace,
+			}
+
+			mappingConfigMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      KofRegionalClusterMappingConfigMapName,
+					Namespace: ReleaseNamespace,
+				},
+				Data: map[string]string{
+					RegionalClusterMappingKey: fmt.Sprintf(`
+- regionalClusterName: %s
+  cloud: adopted
+  clusterSelector:
+    matchLabels:
+      example.com/site: edge
+`, regionalClusterDeploymentName),
+				},
+			}
+			Expect(k8sClient.Create(ctx, mappingConfigMap)).To(Succeed())
+
+			By("creating adopted child ClusterDeployment matching the mapping")
+			adoptedChildClusterDeployment := &kcmv1beta1.ClusterDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      adoptedChildClusterDeploymentName,
+					Namespace: defaultNamespace,
+					Labels: map[string]string{
+						KofClusterRoleLabel: "child",
+						"example.com/site":  "edge",
+					},
+				},
+				Spec: kcmv1beta1.ClusterDeploymentSpec{
+					Template: "adopted-cluster-1-0-0",
+					Config:   &apiextensionsv1.JSON{Raw: []byte(`{}`)},
+				},
+			}
+			Expect(k8sClient.Create(ctx, adoptedChildClusterDeployment)).To(Succeed())
+
+			DeferCleanup(func() {
+				By("cleanup adopted child ClusterDeployment")
+				deleteClusterDeployment(adoptedChildClusterDeploymentNamespacedName)
+
+				configMap := &corev1.ConfigMap{}
+				if err := k8sClient.Get(ctx, types.NamespacedName{
+					Name:      "kof-cluster-config-" + adoptedChildClusterDeploymentName,
+					Namespace: defaultNamespace,
+				}, configMap); err == nil {
+					Expect(k8sClient.Delete(ctx, configMap)).To(Succeed())
+				}
+
+				By("cleanup regional cluster mapping ConfigMap")
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, mappingConfigMap))).To(Succeed())
+			})
+
+			Expect(controllerReconciler.mapRegionalClusterMappingToChildClusterDeployments(ctx, mappingConfigMap)).
+				To(ContainElement(reconcile.Request{NamespacedName: adoptedChildClusterDeploymentNamespacedName}))
+
+			By("reconciling adopted child ClusterDeployment")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: adoptedChildClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      "kof-cluster-config-" + adoptedChildClusterDeploymentName,
+				Namespace: defaultNamespace,
+			}, configMap)).To(Succeed())
+			Expect(configMap.Data[RegionalClusterNameKey]).To(Equal(regionalClusterDeploymentName))
+
+			By("failing discovery when the mapping is removed")
+			Expect(k8sClient.Delete(ctx, mappingConfigMap)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: adoptedChildClusterDeploymentNamespacedName,
+			})
+			Expect(err).To(MatchError(ContainSubstring(KofRegionalClusterMappingConfigMapName)))
+		})
+
+		It("should fail over child cluster to a ready regional cluster", func() {
+			const backupRegionalClusterDeploymentName = "test-regional-backup"
+			const failoverChildClusterDeploymentName = "test-child-failover"
+			const notFailoverChildClusterDeploymentName = "test-child-no-failover"
+
+			backupRegionalClusterDeploymentNamespacedName := types.NamespacedName{
+				Name:      backupRegionalClusterDeploymentName,
+				Namespace: defaultNamespace,
+			}
+
+			createClusterDeployment(
+				backupRegionalClusterDeploymentName,
+				regionalClusterDeploymentLabels,
+				map[string]string{},
+				fmt.Sprintf(`{
+					"region": "us-east-2",
+					"clusterAnnotations": {"%s": "%s"}
+				}`, KofRegionalDomainAnnotation, "test-aws-ue2-backup.kof.example.com"),
+			)
+
+			createClusterDeployment(
+				failoverChildClusterDeploymentName,
+				map[string]string{KofClusterRoleLabel: "child"},
+				map[string]string{KofRegionalFailoverAnnotation: "true"},
+				childClusterDeploymentConfig,
+			)
+
+			createClusterDeployment(
+				notFailoverChildClusterDeploymentName,
+				map[string]string{KofClusterRoleLabel: "child"},
+				map[string]string{},
+				childClusterDeploymentConfig,
+			)
+
+			DeferCleanup(func() {
+				for _, name := range []string{
+					failoverChildClusterDeploymentName,
+					notFailoverChildClusterDeploymentName,
+				} {
+					By("cleanup child ClusterDeployment " + name)
+					deleteClusterDeployment(types.NamespacedName{Name: name, Namespace: defaultNamespace})
+
+					configMap := &corev1.ConfigMap{}
+					configMapNamespacedName := types.NamespacedName{
+						Name:      "kof-cluster-config-" + name,
+						Namespace: defaultNamespace,
+					}
+					if err := k8sClient.Get(ctx, configMapNamespacedName, configMap); err == nil {
+						Expect(k8sClient.Delete(ctx, configMap)).To(Succeed())
+					}
+				}
+
+				By("cleanup backup regional ClusterDeployment")
+				deleteClusterDeployment(backupRegionalClusterDeploymentNamespacedName)
+			})
+
+			expectRegionalClusterName := func(childClusterDeploymentName string, regionalClusterName string) {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: childClusterDeploymentName, Namespace: defaultNamespace},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				configMap := &corev1.ConfigMap{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{
+					Name:      "kof-cluster-config-" + childClusterDeploymentName,
+					Namespace: defaultNamespace,
+				}, configMap)).To(Succeed())
+				Expect(configMap.Data[RegionalClusterNameKey]).To(Equal(regionalClusterName))
+			}
+
+			setReady := func(namespacedName types.NamespacedName, status metav1.ConditionStatus) {
+				clusterDeployment := &kcmv1beta1.ClusterDeployment{}
+				Expect(k8sClient.Get(ctx, namespacedName, clusterDeployment)).To(Succeed())
+				meta.SetStatusCondition(&clusterDeployment.Status.Conditions, metav1.Condition{
+					Type:    kcmv1beta1.ReadyCondition,
+					Status:  status,
+					Reason:  "Test",
+					Message: "Test",
+				})
+				Expect(k8sClient.Status().Update(ctx, clusterDeployment)).To(Succeed())
+			}
+
+			By("discovering the first regional cluster by name")
+			expectRegionalClusterName(failoverChildClusterDeploymentName, regionalClusterDeploymentName)
+			expectRegionalClusterName(notFailoverChildClusterDeploymentName, regionalClusterDeploymentName)
+
+			By("failing over to the backup regional cluster when the current one is not ready")
+			setReady(regionalClusterDeploymentNamespacedName, metav1.ConditionFalse)
+			expectRegionalClusterName(failoverChildClusterDeploymentName, backupRegionalClusterDeploymentName)
+			expectRegionalClusterName(notFailoverChildClusterDeploymentName, regionalClusterDeploymentName)
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      "kof-cluster-config-" + failoverChildClusterDeploymentName,
+				Namespace: defaultNamespace,
+			}, configMap)).To(Succeed())
+			Expect(configMap.Data[WriteMetricsKey]).To(Equal(
+				"https://vmauth.test-aws-ue2-backup.kof.example.com/vm/insert/0/prometheus/api/v1/write",
+			))
+
+			By("keeping the backup regional cluster when the first one is ready again")
+			setReady(regionalClusterDeploymentNamespacedName, metav1.ConditionTrue)
+			expectRegionalClusterName(failoverChildClusterDeploymentName, backupRegionalClusterDeploymentName)
+
+			By("keeping the current regional cluster when no regional cluster is ready")
+			setReady(regionalClusterDeploymentNamespacedName, metav1.ConditionFalse)
+			setReady(backupRegionalClusterDeploymentNamespacedName, metav1.ConditionFalse)
+			expectRegionalClusterName(failoverChildClusterDeploymentName, backupRegionalClusterDeploymentName)
+		})
+
+		It("should pass only Ready transitions of status updates of regional clusters", func() {
+			withReady := func(status metav1.ConditionStatus, message string) *kcmv1beta1.ClusterDeployment {
+				clusterDeployment := &kcmv1beta1.ClusterDeployment{}
+				meta.SetStatusCondition(&clusterDeployment.Status.Conditions, metav1.Condition{
+					Type:    kcmv1beta1.ReadyCondition,
+					Status:  status,
+					Reason:  "Test",
+					Message: message,
+				})
+				return clusterDeployment
+			}
+			readyPredicate := readyConditionChangedPredicate()
+
+			Expect(readyPredicate.Update(event.UpdateEvent{
+				ObjectOld: withReady(metav1.ConditionTrue, "first"),
+				ObjectNew: withReady(metav1.ConditionTrue, "second"),
+			})).To(BeFalse())
+			Expect(readyPredicate.Update(event.UpdateEvent{
+				ObjectOld: withReady(metav1.ConditionTrue, "first"),
+				ObjectNew: withReady(metav1.ConditionFalse, "first"),
+			})).To(BeTrue())
+			Expect(readyPredicate.Update(event.UpdateEvent{
+				ObjectOld: &kcmv1beta1.ClusterDeployment{},
+				ObjectNew: withReady(metav1.ConditionUnknown, "first"),
+			})).To(BeTrue())
+		})
+
+		It("should add write endpoints of additional regional clusters to child cluster ConfigMap", func() {
+			const drRegionalClusterDeploymentName = "test-regional-dr"
+
+			drRegionalClusterDeploymentNamespacedName := types.NamespacedName{
+				Name:      drRegionalClusterDeploymentName,
+				Namespace: defaultNamespace,
+			}
+
+			createClusterDeployment(
+				drRegionalClusterDeploymentName,
+				regionalClusterDeploymentLabels,
+				map[string]string{},
+				fmt.Sprintf(`{
+					"region": "us-west-2",
+					"clusterAnnotations": {"%s": "%s"}
+				}`, KofRegionalDomainAnnotation, "test-aws-uw2.kof.example.com"),
+			)
+
+			DeferCleanup(func() {
+				By("cleanup DR regional ClusterDeployment")
+				deleteClusterDeployment(drRegionalClusterDeploymentNamespacedName)
+			})
+
+			By("adding DR regional cluster to child ClusterDeployment")
+			childClusterDeployment := &kcmv1beta1.ClusterDeployment{}
+			Expect(k8sClient.Get(ctx, childClusterDeploymentNamespacedName, childClusterDeployment)).To(Succeed())
+			childClusterDeployment.Annotations = map[string]string{
+				KofAdditionalRegionalClusterNamesAnnotation: drRegionalClusterDeploymentName,
+			}
+			Expect(k8sClient.Update(ctx, childClusterDeployment)).To(Succeed())
+
+			Expect(controllerReconciler.mapRegionalToChildClusterDeployments(ctx, &kcmv1beta1.ClusterDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      drRegionalClusterDeploymentName,
+					Namespace: defaultNamespace,
+					Labels:    regionalClusterDeploymentLabels,
+				},
+			})).To(ContainElement(reconcile.Request{NamespacedName: childClusterDeploymentNamespacedName}))
+
+			By("reconciling child ClusterDeployment")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("reading ConfigMap with primary and additional endpoints")
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, childClusterConfigMapNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data[WriteMetricsKey]).To(Equal(
+				"https://vmauth.test-aws-ue2.kof.example.com/vm/insert/0/prometheus/api/v1/write",
+			))
+			Expect(configMap.Data[AdditionalWriteEndpointsKey]).To(MatchYAML(`
+test-regional-dr:
+  metrics:
+    endpoint: https://vmauth.test-aws-uw2.kof.example.com/vm/insert/0/prometheus/api/v1/write
+  logs:
+    endpoint: https://vmauth.test-aws-uw2.kof.example.com/vli/insert/opentelemetry/v1/logs
+  traces:
+    endpoint: https://jaeger.test-aws-uw2.kof.example.com/collector
+`))
+
+			By("removing DR regional cluster from child ClusterDeployment")
+			Expect(k8sClient.Get(ctx, childClusterDeploymentNamespacedName, childClusterDeployment)).To(Succeed())
+			delete(childClusterDeployment.Annotations, KofAdditionalRegionalClusterNamesAnnotation)
+			Expect(k8sClient.Update(ctx, childClusterDeployment)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, childClusterConfigMapNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data).NotTo(HaveKey(AdditionalWriteEndpointsKey))
+		})
+
+		It("should isolate tenant of child cluster in regional cluster", func() {
+			tenantMappingConfigMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      KofTenantMappingConfigMapName,
+					Namespace: ReleaseNamespace,
+				},
+				Data: map[string]string{
+					TenantMappingKey: `
+- name: team-a
+  accountID: 42
+  namespaces: [team-a]
+`,
+				},
+			}
+			Expect(k8sClient.Create(ctx, tenantMappingConfigMap)).To(Succeed())
+			DeferCleanup(func() {
+				By("cleanup tenant mapping ConfigMap")
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, tenantMappingConfigMap))).To(Succeed())
+			})
+
+			Expect(controllerReconciler.mapKofConfigMapToClusterDeployments(ctx, tenantMappingConfigMap)).To(ContainElements(
+				reconcile.Request{NamespacedName: regionalClusterDeploymentNamespacedName},
+				reconcile.Request{NamespacedName: childClusterDeploymentNamespacedName},
+			))
+
+			By("reconciling regional ClusterDeployment")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: regionalClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			tenantPromxyServerGroupNamespacedName := types.NamespacedName{
+				Name:      regionalClusterDeploymentName + "-metrics-team-a",
+				Namespace: ReleaseNamespace,
+			}
+			tenantGrafanaDatasourceNamespacedName := types.NamespacedName{
+				Name:      regionalClusterDeploymentName + "-logs-team-a",
+				Namespace: ReleaseNamespace,
+			}
+
+			By("reading PromxyServerGroup of tenant")
+			promxyServerGroup := &kofv1beta1.PromxyServerGroup{}
+			Expect(k8sClient.Get(ctx, tenantPromxyServerGroupNamespacedName, promxyServerGroup)).To(Succeed())
+			Expect(promxyServerGroup.Labels).To(HaveKeyWithValue(PromxySecretNameLabel, DefaultPromxySecretName))
+			Expect(promxyServerGroup.Spec.Labels).To(Equal(map[string]string{TenantSeriesLabel: "team-a"}))
+			Expect(promxyServerGroup.Labels).To(HaveKeyWithValue(KofTenantLabel, "team-a"))
+			Expect(promxyServerGroup.Spec.PathPrefix).To(Equal("/vm/select/42/prometheus"))
+
+			By("reading GrafanaDatasource of tenant")
+			grafanaDatasource := &grafanav1beta1.GrafanaDatasource{}
+			Expect(k8sClient.Get(ctx, tenantGrafanaDatasourceNamespacedName, grafanaDatasource)).To(Succeed())
+			Expect(grafanaDatasource.Spec.Datasource.JSONData).To(MatchJSON(
+				`{"httpHeaderName1": "AccountID", "httpHeaderName2": "ProjectID"}`,
+			))
+			Expect(grafanaDatasource.Spec.Datasource.SecureJSONData).To(MatchJSON(
+				`{"basicAuthPassword": "${password}", "httpHeaderValue1": "42", "httpHeaderValue2": "0"}`,
+			))
+
+			By("reconciling child ClusterDeployment selecting the tenant by label")
+			childClusterDeployment := &kcmv1beta1.ClusterDeployment{}
+			Expect(k8sClient.Get(ctx, childClusterDeploymentNamespacedName, childClusterDeployment)).To(Succeed())
+			childClusterDeployment.Labels[KofTenantLabel] = "team-a"
+			Expect(k8sClient.Update(ctx, childClusterDeployment)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, childClusterConfigMapNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKeyWithValue(TenantKey, "team-a"))
+			Expect(configMap.Data).To(HaveKeyWithValue(
+				WriteMetricsKey, "https://vmauth.test-aws-ue2.kof.example.com/vm/insert/42/prometheus/api/v1/write",
+			))
+			Expect(configMap.Data).To(HaveKeyWithValue(
+				ReadMetricsKey, "https://vmauth.test-aws-ue2.kof.example.com/vm/select/42/prometheus",
+			))
+			Expect(configMap.Data[WriteLogsHeadersKey]).To(MatchYAML(`{AccountID: "42", ProjectID: "0"}`))
+
+			By("deleting objects of removed tenant")
+			Expect(k8sClient.Delete(ctx, tenantMappingConfigMap)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: regionalClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, tenantPromxyServerGroupNamespacedName, &kofv1beta1.PromxyServerGroup{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			err = k8sClient.Get(ctx, tenantGrafanaDatasourceNamespacedName, &grafanav1beta1.GrafanaDatasource{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			By("failing child ClusterDeployment of removed tenant")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).To(MatchError(ContainSubstring("tenant is not found")))
+		})
+
+		It("should render endpoints of child cluster from endpoint templates", func() {
+			endpointTemplatesConfigMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      KofEndpointTemplatesConfigMapName,
+					Namespace: ReleaseNamespace,
+				},
+				Data: map[string]string{
+					EndpointTemplatesKey: `
+default:
+  writeMetrics: "https://metrics-{{ .ClusterName }}.{{ .Domain }}/vm/insert/{{ .TenantID }}/prometheus/api/v1/write"
+  writeTraces: "https://traces-{{ .ClusterName }}.{{ .Domain }}/collector"
+`,
+				},
+			}
+			Expect(k8sClient.Create(ctx, endpointTemplatesConfigMap)).To(Succeed())
+			DeferCleanup(func() {
+				By("cleanup endpoint templates ConfigMap")
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, endpointTemplatesConfigMap))).To(Succeed())
+			})
+
+			Expect(controllerReconciler.mapKofConfigMapToClusterDeployments(ctx, endpointTemplatesConfigMap)).To(
+				ContainElement(reconcile.Request{NamespacedName: childClusterDeploymentNamespacedName}),
+			)
+
+			By("reconciling child ClusterDeployment")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, childClusterConfigMapNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKeyWithValue(
+				WriteMetricsKey,
+				"https://metrics-"+regionalClusterDeploymentName+".test-aws-ue2.kof.example.com"+
+					"/vm/insert/0/prometheus/api/v1/write",
+			))
+			Expect(configMap.Data).To(HaveKeyWithValue(
+				WriteTracesKey,
+				"https://traces-"+regionalClusterDeploymentName+".test-aws-ue2.kof.example.com/collector",
+			))
+			Expect(configMap.Data).To(HaveKeyWithValue(
+				WriteLogsKey, "https://vmauth.test-aws-ue2.kof.example.com/vli/insert/opentelemetry/v1/logs",
+			))
+
+			By("failing child ClusterDeployment with invalid endpoint templates")
+			endpointTemplatesConfigMap.Data[EndpointTemplatesKey] = `{"default": {"writeEvents": "https://{{ .Domain }}"}}`
+			Expect(k8sClient.Update(ctx, endpointTemplatesConfigMap)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).To(MatchError(ContainSubstring("unsupported endpoint")))
+		})
+
+		It("should probe endpoints of child cluster", func() {
+			var vmauthHealthy atomic.Bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				username, password, ok := r.BasicAuth()
+				if !ok || username != "probe-user" || password != "probe-password" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				switch {
+				case r.URL.Path == jaegerHealthPath:
+					w.WriteHeader(http.StatusOK)
+				case r.URL.Path == vmauthHealthPath && vmauthHealthy.Load():
+					_, _ = w.Write([]byte("OK"))
+				default:
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			DeferCleanup(server.Close)
+
+			credentialsSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      KofStorageSecretName,
+					Namespace: ReleaseNamespace,
+				},
+				Data: map[string][]byte{
+					"username": []byte("probe-user"),
+					"password": []byte("probe-password"),
+				},
+			}
+			Expect(k8sClient.Create(ctx, credentialsSecret)).To(Succeed())
+			DeferCleanup(func() {
+				By("cleanup credentials Secret")
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, credentialsSecret))).To(Succeed())
+			})
+
+			endpointTemplatesConfigMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      KofEndpointTemplatesConfigMapName,
+					Namespace: ReleaseNamespace,
+				},
+				Data: map[string]string{
+					EndpointTemplatesKey: fmt.Sprintf(`
+default:
+  readMetrics: "%[1]s/vm/select/{{ .TenantID }}/prometheus"
+  writeMetrics: "%[1]s/vm/insert/{{ .TenantID }}/prometheus/api/v1/write"
+  writeLogs: "%[1]s/vli/insert/opentelemetry/v1/logs"
+  writeTraces: "%[1]s/collector"
+`, server.URL),
+				},
+			}
+			Expect(k8sClient.Create(ctx, endpointTemplatesConfigMap)).To(Succeed())
+			DeferCleanup(func() {
+				By("cleanup endpoint templates ConfigMap")
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, endpointTemplatesConfigMap))).To(Succeed())
+			})
+
+			endpointProber := NewEndpointProber(k8sClient, time.Minute)
+			controllerReconciler.EndpointProber = endpointProber
+
+			By("reconciling child ClusterDeployment without probing endpoints")
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(reconcile.Result{}))
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, childClusterConfigMapNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKeyWithValue(WriteTracesKey, server.URL+"/collector"))
+
+			childKofCluster := &kofv1beta1.KofCluster{}
+			Expect(k8sClient.Get(ctx, childClusterDeploymentNamespacedName, childKofCluster)).To(Succeed())
+			Expect(meta.FindStatusCondition(
+				childKofCluster.Status.Conditions, kofv1beta1.KofClusterEndpointsReachableCondition,
+			)).To(BeNil())
+
+			By("probing endpoints of child cluster with unhealthy vmauth")
+			endpointProber.ProbeAll(ctx)
+
+			Expect(k8sClient.Get(ctx, childClusterDeploymentNamespacedName, childKofCluster)).To(Succeed())
+			condition := meta.FindStatusCondition(
+				childKofCluster.Status.Conditions, kofv1beta1.KofClusterEndpointsReachableCondition,
+			)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Message).To(ContainSubstring(WriteMetricsKey + ": GET " + server.URL + "/health"))
+			Expect(condition.Message).To(ContainSubstring(WriteLogsKey))
+			Expect(condition.Message).NotTo(ContainSubstring(WriteTracesKey))
+
+			By("keeping the condition when child ClusterDeployment is reconciled")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, childClusterDeploymentNamespacedName, childKofCluster)).To(Succeed())
+			Expect(meta.IsStatusConditionFalse(
+				childKofCluster.Status.Conditions, kofv1beta1.KofClusterEndpointsReachableCondition,
+			)).To(BeTrue())
+
+			By("probing endpoints of child cluster with healthy vmauth in the background")
+			vmauthHealthy.Store(true)
+			proberCtx, cancelProber := context.WithCancel(ctx)
+			proberDone := make(chan error)
+			go func() { proberDone <- endpointProber.Start(proberCtx) }()
+
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, childClusterDeploymentNamespacedName, childKofCluster)).To(Succeed())
+				g.Expect(meta.IsStatusConditionTrue(
+					childKofCluster.Status.Conditions, kofv1beta1.KofClusterEndpointsReachableCondition,
+				)).To(BeTrue())
+			}).Should(Succeed())
+			cancelProber()
+			Eventually(proberDone).Should(Receive(BeNil()))
+
+			By("removing the condition when the probe is disabled")
+			controllerReconciler.EndpointProber = nil
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, childClusterDeploymentNamespacedName, childKofCluster)).To(Succeed())
+			Expect(meta.FindStatusCondition(
+				childKofCluster.Status.Conditions, kofv1beta1.KofClusterEndpointsReachableCondition,
+			)).To(BeNil())
+		})
+
+		It("should create and revoke own credentials of child cluster", func() {
+			credentialsSecretNamespacedName := types.NamespacedName{
+				Name:      "kof-vmuser-" + childClusterDeploymentName,
+				Namespace: defaultNamespace,
+			}
+			regionalProfileNamespacedName := types.NamespacedName{
+				Name:      childClusterDeploymentName + "-kof-vmuser",
+				Namespace: defaultNamespace,
+			}
+			childProfileNamespacedName := types.NamespacedName{
+				Name:      childClusterDeploymentName + "-kof-vmuser-credentials",
+				Namespace: defaultNamespace,
+			}
+			vmUserTemplateNamespacedName := types.NamespacedName{
+				Name:      "kof-vmuser-template-" + childClusterDeploymentName,
+				Namespace: defaultNamespace,
+			}
+
+			By("opting child ClusterDeployment in to its own credentials")
+			childClusterDeployment := &kcmv1beta1.ClusterDeployment{}
+			Expect(k8sClient.Get(ctx, childClusterDeploymentNamespacedName, childClusterDeployment)).To(Succeed())
+			metav1.SetMetaDataAnnotation(&childClusterDeployment.ObjectMeta, KofChildCredentialsAnnotation, "true")
+			Expect(k8sClient.Update(ctx, childClusterDeployment)).To(Succeed())
+			DeferCleanup(func() {
+				By("cleanup credentials objects of child cluster")
+				for _, item := range childCredentialsObjects(childClusterDeployment) {
+					Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, item.object))).To(Succeed())
+				}
+			})
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("reading credentials Secret and ConfigMap of child cluster")
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, credentialsSecretNamespacedName, secret)).To(Succeed())
+			Expect(secret.Data).To(HaveKeyWithValue("username", []byte(credentialsSecretNamespacedName.Name)))
+			Expect(secret.Data["password"]).NotTo(BeEmpty())
+			Expect(secret.OwnerReferences).To(HaveLen(1))
+			Expect(secret.OwnerReferences[0].Name).To(Equal(childClusterDeploymentName))
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, childClusterConfigMapNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKeyWithValue(CredentialsSecretNameKey, credentialsSecretNamespacedName.Name))
+
+			By("reading Profiles deploying the credentials to regional and child clusters")
+			regionalProfile := &sveltosv1beta1.Profile{}
+			Expect(k8sClient.Get(ctx, regionalProfileNamespacedName, regionalProfile)).To(Succeed())
+			Expect(regionalProfile.Spec.ClusterRefs).To(HaveLen(1))
+			Expect(regionalProfile.Spec.ClusterRefs[0].Name).To(Equal(regionalClusterDeploymentName))
+			Expect(regionalProfile.Spec.PolicyRefs).To(HaveLen(2))
+			Expect(regionalProfile.Spec.TemplateResourceRefs[0].Resource.Name).To(Equal(credentialsSecretNamespacedName.Name))
+
+			childProfile := &sveltosv1beta1.Profile{}
+			Expect(k8sClient.Get(ctx, childProfileNamespacedName, childProfile)).To(Succeed())
+			Expect(childProfile.Spec.ClusterRefs).To(HaveLen(1))
+			Expect(childProfile.Spec.ClusterRefs[0].Name).To(Equal(childClusterDeploymentName))
+			Expect(childProfile.Spec.PolicyRefs).To(HaveLen(1))
+
+			vmUserTemplate := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, vmUserTemplateNamespacedName, vmUserTemplate)).To(Succeed())
+			Expect(vmUserTemplate.Annotations).To(HaveKeyWithValue("projectsveltos.io/template", "true"))
+			Expect(vmUserTemplate.Data["vmuser.yaml"]).To(ContainSubstring("/vm/insert/0/.*"))
+			Expect(vmUserTemplate.Data["vmuser.yaml"]).NotTo(ContainSubstring("/vls/"))
+			Expect(vmUserTemplate.Data["vmuser.yaml"]).To(ContainSubstring("namespace: kof\n"))
+			Expect(vmUserTemplate.Data["vmuser.yaml"]).To(ContainSubstring("http://vminsert-cluster.kof.svc:8480"))
+
+			By("reconciling child ClusterDeployment again without changing credentials")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			unchangedSecret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, credentialsSecretNamespacedName, unchangedSecret)).To(Succeed())
+			Expect(unchangedSecret.Data).To(Equal(secret.Data))
+			unchangedProfile := &sveltosv1beta1.Profile{}
+			Expect(k8sClient.Get(ctx, regionalProfileNamespacedName, unchangedProfile)).To(Succeed())
+			Expect(unchangedProfile.ResourceVersion).To(Equal(regionalProfile.ResourceVersion))
+
+			By("revoking credentials when child ClusterDeployment opts out")
+			Expect(k8sClient.Get(ctx, childClusterDeploymentNamespacedName, childClusterDeployment)).To(Succeed())
+			delete(childClusterDeployment.Annotations, KofChildCredentialsAnnotation)
+			Expect(k8sClient.Update(ctx, childClusterDeployment)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, credentialsSecretNamespacedName, &corev1.Secret{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			err = k8sClient.Get(ctx, regionalProfileNamespacedName, &sveltosv1beta1.Profile{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			err = k8sClient.Get(ctx, childProfileNamespacedName, &sveltosv1beta1.Profile{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, childClusterConfigMapNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data).NotTo(HaveKey(CredentialsSecretNameKey))
+		})
+
+		It("should create profile", func() {
+			By("reading child ClusterDeployment")
+			clusterDeployment := &kcmv1beta1.ClusterDeployment{}
+			err := k8sClient.Get(ctx, childClusterDeploymentNamespacedName, clusterDeployment)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("reconciling child ClusterDeployment")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("reading profile")
+			profile := &sveltosv1beta1.Profile{}
+			err = k8sClient.Get(ctx, profileDeploymentName, profile)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("reconciling unchanged child ClusterDeployment without profile updates")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: childClusterDeploymentNamespacedName,
+			})
 			Expect(err).NotTo(HaveOccurred())
+			unchangedProfile := &sveltosv1beta1.Profile{}
+			Expect(k8sClient.Get(ctx, profileDeploymentName, unchangedProfile)).To(Succeed())
+			Expect(unchangedProfile.ResourceVersion).To(Equal(profile.ResourceVersion))
 		})
 	})
 })
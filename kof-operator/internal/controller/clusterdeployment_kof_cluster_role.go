@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,11 +21,14 @@ import (
 	sveltosv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
 )
 
 const prefix = "k0rdent.mirantis.com/"
@@ -34,31 +40,49 @@ const KofRegionalClusterNameLabel = prefix + "kof-regional-cluster-name"
 // Annotations:
 const KofRegionalDomainAnnotation = prefix + "kof-regional-domain"
 const KofRegionalHTTPClientConfigAnnotation = prefix + "kof-http-config"
+const KofRegionalFailoverAnnotation = prefix + "kof-regional-failover"
+const KofAdditionalRegionalClusterNamesAnnotation = prefix + "kof-additional-regional-cluster-names"
+const KofGrafanaDatasourcesAnnotation = prefix + "kof-grafana-datasources"
 const WriteMetricsAnnotation = prefix + "kof-write-metrics-endpoint"
 const ReadMetricsAnnotation = prefix + "kof-read-metrics-endpoint"
 const WriteLogsAnnotation = prefix + "kof-write-logs-endpoint"
 const ReadLogsAnnotation = prefix + "kof-read-logs-endpoint"
 const WriteTracesAnnotation = prefix + "kof-write-traces-endpoint"
+const ReadTracesAnnotation = prefix + "kof-read-traces-endpoint"
 
-// Endpoints for Sprintf:
+// Set by the operator to detect role changes, not by users:
+const KofAppliedClusterRoleAnnotation = prefix + "kof-applied-cluster-role"
+
+// Endpoint templates, overridden by `KofEndpointTemplatesConfigMapName`:
 var defaultEndpoints = map[string]string{
-	WriteMetricsAnnotation: "https://vmauth.%s/vm/insert/0/prometheus/api/v1/write",
-	ReadMetricsAnnotation:  "https://vmauth.%s/vm/select/0/prometheus",
-	WriteLogsAnnotation:    "https://vmauth.%s/vli/insert/opentelemetry/v1/logs",
-	ReadLogsAnnotation:     "https://vmauth.%s/vls",
-	WriteTracesAnnotation:  "https://jaeger.%s/collector",
+	WriteMetricsAnnotation: "https://vmauth.{{ .Domain }}/vm/insert/{{ .TenantID }}/prometheus/api/v1/write",
+	ReadMetricsAnnotation:  "https://vmauth.{{ .Domain }}/vm/select/{{ .TenantID }}/prometheus",
+	WriteLogsAnnotation:    "https://vmauth.{{ .Domain }}/vli/insert/opentelemetry/v1/logs",
+	ReadLogsAnnotation:     "https://vmauth.{{ .Domain }}/vls",
+	WriteTracesAnnotation:  "https://jaeger.{{ .Domain }}/collector",
+	ReadTracesAnnotation:   "https://jaeger.{{ .Domain }}",
 }
 var istioEndpoints = map[string]string{
-	ReadLogsAnnotation:    "http://%s-logs-select:9471",
-	ReadMetricsAnnotation: "http://%s-vmselect:8481/select/0/prometheus",
+	ReadLogsAnnotation:    "http://{{ .ClusterName }}-logs-select:9471",
+	ReadMetricsAnnotation: "http://{{ .ClusterName }}-vmselect:8481/select/{{ .TenantID }}/prometheus",
 }
 
+// Optional GrafanaDatasources of the regional cluster, enabled by `KofGrafanaDatasourcesAnnotation`:
+var optionalGrafanaDatasources = []string{"metrics", "traces"}
+
 // Child cluster ConfigMap data keys:
 const RegionalClusterNameKey = "regional_cluster_name"
 const ReadMetricsKey = "read_metrics_endpoint"
 const WriteMetricsKey = "write_metrics_endpoint"
 const WriteLogsKey = "write_logs_endpoint"
 const WriteTracesKey = "write_traces_endpoint"
+const AdditionalWriteEndpointsKey = "additional_write_endpoints"
+const TenantKey = "tenant"
+const WriteLogsHeadersKey = "write_logs_headers"
+const CredentialsSecretNameKey = "credentials_secret_name"
+
+// Finalizers:
+const KofRegionalClusterFinalizer = prefix + "kof-regional-cluster"
 
 // Other:
 const KofStorageSecretName = "storage-vmuser-credentials"
@@ -70,40 +94,251 @@ func (r *ClusterDeploymentReconciler) ReconcileKofClusterRole(
 	ctx context.Context,
 	clusterDeployment *kcmv1beta1.ClusterDeployment,
 ) error {
+	if !clusterDeployment.DeletionTimestamp.IsZero() {
+		if controllerutil.ContainsFinalizer(clusterDeployment, KofRegionalClusterFinalizer) {
+			return r.finalizeRegionalClusterRole(ctx, clusterDeployment)
+		}
+		return nil
+	}
+
+	if err := r.tearDownPreviousClusterRole(ctx, clusterDeployment); err != nil {
+		return err
+	}
+
 	role := clusterDeployment.Labels[KofClusterRoleLabel]
 	if role == "child" {
-		return r.reconcileChildClusterRole(ctx, clusterDeployment)
+		if err := r.reconcileChildClusterRole(ctx, clusterDeployment); err != nil {
+			return err
+		}
 	} else if role == "regional" {
-		return r.reconcileRegionalClusterRole(ctx, clusterDeployment)
+		if err := r.addRegionalClusterFinalizer(ctx, clusterDeployment); err != nil {
+			return err
+		}
+		if err := r.reconcileRegionalClusterRole(ctx, clusterDeployment); err != nil {
+			return err
+		}
+	}
+	return r.setAppliedClusterRole(ctx, clusterDeployment, role)
+}
+
+// Deletes the objects of the role applied before, if the role label is changed or removed.
+// Objects depending on the regional cluster of a child are updated by `reconcileChildClusterRole`.
+func (r *ClusterDeploymentReconciler) tearDownPreviousClusterRole(
+	ctx context.Context,
+	clusterDeployment *kcmv1beta1.ClusterDeployment,
+) error {
+	role := clusterDeployment.Labels[KofClusterRoleLabel]
+	previousRole := clusterDeployment.Annotations[KofAppliedClusterRoleAnnotation]
+	if previousRole == role && previousRole != "" {
+		return nil
+	}
+
+	// The finalizer detects regional clusters reconciled before the annotation was added.
+	if role != "regional" && controllerutil.ContainsFinalizer(clusterDeployment, KofRegionalClusterFinalizer) {
+		if err := r.finalizeRegionalClusterRole(ctx, clusterDeployment); err != nil {
+			return err
+		}
+	}
+
+	if previousRole == "child" && role != "child" {
+		if err := r.deleteChildClusterRoleObjects(ctx, clusterDeployment); err != nil {
+			return err
+		}
+	}
+
+	if previousRole != "" && previousRole != role {
+		utils.LogEvent(
+			ctx,
+			"ClusterRoleChanged",
+			"Objects of previous kof cluster role are deleted",
+			clusterDeployment,
+			nil,
+			"previousRole", previousRole,
+			"role", role,
+		)
 	}
 	return nil
 }
 
-func (r *ClusterDeploymentReconciler) reconcileChildClusterRole(
+// Deletes the objects created by `reconcileChildClusterRole`.
+// They are owned by the child ClusterDeployment, so only a role change needs this.
+func (r *ClusterDeploymentReconciler) deleteChildClusterRoleObjects(
 	ctx context.Context,
 	childClusterDeployment *kcmv1beta1.ClusterDeployment,
 ) error {
-	log := log.FromContext(ctx)
+	objects := []struct {
+		object            client.Object
+		objectDescription string
+	}{
+		{&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:      "kof-cluster-config-" + childClusterDeployment.Name,
+			Namespace: childClusterDeployment.Namespace,
+		}}, "child cluster ConfigMap"},
+		{&sveltosv1beta1.Profile{ObjectMeta: metav1.ObjectMeta{
+			Name:      remotesecret.CopyRemoteSecretProfileName(childClusterDeployment.Name),
+			Namespace: childClusterDeployment.Namespace,
+		}}, "Profile"},
+	}
+	objects = append(objects, childCredentialsObjects(childClusterDeployment)...)
+	for _, item := range objects {
+		if err := r.deleteIfManaged(ctx, item.object, item.objectDescription); err != nil {
+			utils.LogEvent(
+				ctx,
+				"ChildObjectDeletionFailed",
+				"Failed to delete "+item.objectDescription+" of child cluster",
+				childClusterDeployment,
+				err,
+				"objectName", item.object.GetName(),
+				"objectNamespace", item.object.GetNamespace(),
+			)
+			return err
+		}
+	}
+	return nil
+}
 
-	configMap := &corev1.ConfigMap{}
-	configMapName := "kof-cluster-config-" + childClusterDeployment.Name
-	err := r.Get(ctx, types.NamespacedName{
-		Name:      configMapName,
-		Namespace: childClusterDeployment.Namespace,
-	}, configMap)
-	if err != nil && !errors.IsNotFound(err) {
-		log.Error(
-			err, "cannot read existing child cluster ConfigMap",
-			"configMapName", configMapName,
+// Stores the applied `role` in the annotation, so `tearDownPreviousClusterRole` detects its change.
+func (r *ClusterDeploymentReconciler) setAppliedClusterRole(
+	ctx context.Context,
+	clusterDeployment *kcmv1beta1.ClusterDeployment,
+	role string,
+) error {
+	previousRole, ok := clusterDeployment.Annotations[KofAppliedClusterRoleAnnotation]
+	if previousRole == role && (ok || role == "") {
+		return nil
+	}
+
+	// Patching only the annotation keeps concurrent changes of the ClusterDeployment.
+	original := clusterDeployment.DeepCopy()
+	if role == "" {
+		delete(clusterDeployment.Annotations, KofAppliedClusterRoleAnnotation)
+	} else {
+		if clusterDeployment.Annotations == nil {
+			clusterDeployment.Annotations = map[string]string{}
+		}
+		clusterDeployment.Annotations[KofAppliedClusterRoleAnnotation] = role
+	}
+
+	if err := r.Patch(ctx, clusterDeployment, client.MergeFrom(original)); err != nil {
+		log.FromContext(ctx).Error(
+			err, "cannot store applied kof cluster role",
+			"clusterDeploymentName", clusterDeployment.Name,
+			"annotation", KofAppliedClusterRoleAnnotation,
 		)
 		return err
 	}
-	if err == nil {
-		// Logging nothing as we have a lot of frequent `status` updates to ignore here.
-		// Cannot add `WithEventFilter(predicate.GenerationChangedPredicate{})`
-		// to `SetupWithManager` of reconciler shared with istio which needs `status` updates.
+	return nil
+}
+
+// Regional objects in the release namespace cannot have owner references to ClusterDeployment,
+// so the finalizer is used to delete them before the regional ClusterDeployment is deleted.
+func (r *ClusterDeploymentReconciler) addRegionalClusterFinalizer(
+	ctx context.Context,
+	regionalClusterDeployment *kcmv1beta1.ClusterDeployment,
+) error {
+	if !controllerutil.AddFinalizer(regionalClusterDeployment, KofRegionalClusterFinalizer) {
 		return nil
 	}
+	if err := r.Update(ctx, regionalClusterDeployment); err != nil {
+		log.FromContext(ctx).Error(
+			err, "cannot add finalizer to regional ClusterDeployment",
+			"regionalClusterDeploymentName", regionalClusterDeployment.Name,
+			"finalizer", KofRegionalClusterFinalizer,
+		)
+		return err
+	}
+	return nil
+}
+
+// Deletes the objects created by `reconcileRegionalClusterRole` and removes the finalizer,
+// when the regional ClusterDeployment is deleted or its role is changed.
+func (r *ClusterDeploymentReconciler) finalizeRegionalClusterRole(
+	ctx context.Context,
+	regionalClusterDeployment *kcmv1beta1.ClusterDeployment,
+) error {
+	log := log.FromContext(ctx)
+	regionalClusterName := regionalClusterDeployment.Name
+
+	releaseNamespace, ok := os.LookupEnv("RELEASE_NAMESPACE")
+	if !ok {
+		return fmt.Errorf("required RELEASE_NAMESPACE env var is not set")
+	}
+
+	objects := []struct {
+		object            client.Object
+		objectDescription string
+	}{
+		{&kofv1beta1.PromxyServerGroup{ObjectMeta: metav1.ObjectMeta{
+			Name:      regionalClusterName + "-metrics",
+			Namespace: releaseNamespace,
+		}}, "PromxyServerGroup"},
+		{&grafanav1beta1.GrafanaDatasource{ObjectMeta: metav1.ObjectMeta{
+			Name:      regionalClusterName + "-logs",
+			Namespace: releaseNamespace,
+		}}, "GrafanaDatasource"},
+		{&grafanav1beta1.GrafanaDatasource{ObjectMeta: metav1.ObjectMeta{
+			Name:      regionalClusterName + "-metrics",
+			Namespace: releaseNamespace,
+		}}, "GrafanaDatasource"},
+		{&grafanav1beta1.GrafanaDatasource{ObjectMeta: metav1.ObjectMeta{
+			Name:      regionalClusterName + "-traces",
+			Namespace: releaseNamespace,
+		}}, "GrafanaDatasource"},
+		// Owned by the regional ClusterDeployment, but deleted now to re-render the rules at once.
+		{&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:      "kof-record-vmrules-" + regionalClusterName,
+			Namespace: regionalClusterDeployment.Namespace,
+		}}, "VMRulesConfigMap"},
+	}
+	for _, item := range objects {
+		if err := r.deleteIfManaged(ctx, item.object, item.objectDescription); err != nil {
+			utils.LogEvent(
+				ctx,
+				"RegionalObjectDeletionFailed",
+				"Failed to delete "+item.objectDescription+" of regional cluster",
+				regionalClusterDeployment,
+				err,
+				"objectName", item.object.GetName(),
+				"objectNamespace", item.object.GetNamespace(),
+			)
+			return err
+		}
+	}
+	if err := r.deleteRemovedTenantObjects(ctx, regionalClusterDeployment, nil); err != nil {
+		return err
+	}
+
+	controllerutil.RemoveFinalizer(regionalClusterDeployment, KofRegionalClusterFinalizer)
+	if err := r.Update(ctx, regionalClusterDeployment); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		log.Error(
+			err, "cannot remove finalizer from regional ClusterDeployment",
+			"regionalClusterDeploymentName", regionalClusterName,
+			"finalizer", KofRegionalClusterFinalizer,
+		)
+		return err
+	}
+
+	utils.LogEvent(
+		ctx,
+		"RegionalObjectsDeleted",
+		"Objects of regional cluster are deleted",
+		regionalClusterDeployment,
+		nil,
+		"regionalClusterDeploymentName", regionalClusterName,
+	)
+	return nil
+}
+
+func (r *ClusterDeploymentReconciler) reconcileChildClusterRole(
+	ctx context.Context,
+	childClusterDeployment *kcmv1beta1.ClusterDeployment,
+) error {
+	log := log.FromContext(ctx)
+
+	configMapName := "kof-cluster-config-" + childClusterDeployment.Name
 
 	regionalClusterName, ok := childClusterDeployment.Labels[KofRegionalClusterNameLabel]
 	regionalClusterDeployment := &kcmv1beta1.ClusterDeployment{}
@@ -119,6 +354,21 @@ func (r *ClusterDeploymentReconciler) reconcileChildClusterRole(
 			)
 			return err
 		}
+		if regionalClusterDeployment.Labels[KofClusterRoleLabel] != "regional" {
+			err := fmt.Errorf(
+				`ClusterDeployment "%s" does not have label "%s: regional"`,
+				regionalClusterName, KofClusterRoleLabel,
+			)
+			utils.LogEvent(
+				ctx,
+				"RegionalClusterRoleNotFound",
+				"Regional cluster of the child has no regional role",
+				childClusterDeployment,
+				err,
+				"regionalClusterName", regionalClusterName,
+			)
+			return err
+		}
 	} else {
 		var err error
 		if regionalClusterDeployment, err = r.discoverRegionalClusterDeploymentByLocation(
@@ -156,42 +406,125 @@ func (r *ClusterDeploymentReconciler) reconcileChildClusterRole(
 		}
 	}
 
+	tenant, err := findTenant(ctx, r.Client, childClusterDeployment)
+	if err != nil {
+		utils.LogEvent(
+			ctx,
+			"TenantNotFound",
+			"Failed to find tenant of the child cluster",
+			childClusterDeployment,
+			err,
+			"label", KofTenantLabel,
+			"configMapName", KofTenantMappingConfigMapName,
+		)
+		return err
+	}
+
+	endpointTemplates, err := readEndpointTemplates(ctx, r.Client)
+	if err != nil {
+		utils.LogEvent(
+			ctx,
+			"InvalidEndpointTemplates",
+			"Failed to read endpoint templates",
+			childClusterDeployment,
+			err,
+			"configMapName", KofEndpointTemplatesConfigMapName,
+		)
+		return err
+	}
+
 	configData := map[string]string{RegionalClusterNameKey: regionalClusterName}
 
 	if _, isIstio := regionalClusterDeployment.Labels[IstioRoleLabel]; !isIstio {
-		regionalClusterDeploymentConfig, err := ReadClusterDeploymentConfig(
-			regionalClusterDeployment.Spec.Config.Raw,
-		)
+		endpoints, err := getChildClusterEndpoints(ctx, regionalClusterDeployment, endpointTemplates, tenant)
 		if err != nil {
-			log.Error(
-				err, "cannot read regional ClusterDeployment config",
-				"regionalClusterDeploymentName", regionalClusterDeployment.Name,
-			)
 			return err
 		}
+		maps.Copy(configData, endpoints)
+	} else if tenant != nil {
+		err := fmt.Errorf("tenants are not supported with istio regional cluster")
+		utils.LogEvent(
+			ctx,
+			"TenantNotSupported",
+			"Tenant of the child cluster is not supported by its regional cluster",
+			childClusterDeployment,
+			err,
+			"tenant", tenant.Name,
+			"regionalClusterName", regionalClusterName,
+		)
+		return err
+	}
 
-		configData[ReadMetricsKey], err = getEndpoint(ctx, ReadMetricsAnnotation, regionalClusterDeployment, regionalClusterDeploymentConfig)
+	if tenant != nil {
+		configData[TenantKey] = tenant.Name
+		logsHeadersYAML, err := yaml.Marshal(tenant.logsHeaders())
 		if err != nil {
+			log.Error(err, "cannot marshal logs headers of tenant", "tenant", tenant.Name)
 			return err
 		}
+		configData[WriteLogsHeadersKey] = string(logsHeadersYAML)
+	}
 
-		configData[WriteMetricsKey], err = getEndpoint(ctx, WriteMetricsAnnotation, regionalClusterDeployment, regionalClusterDeploymentConfig)
+	additionalWriteEndpoints, err := getAdditionalWriteEndpoints(
+		ctx,
+		r.Client,
+		childClusterDeployment,
+		regionalClusterName,
+		endpointTemplates,
+		tenant,
+	)
+	if err != nil {
+		utils.LogEvent(
+			ctx,
+			"AdditionalRegionalClustersInvalid",
+			"Failed to get endpoints of additional regional clusters",
+			childClusterDeployment,
+			err,
+			"annotation", KofAdditionalRegionalClusterNamesAnnotation,
+			"value", childClusterDeployment.Annotations[KofAdditionalRegionalClusterNamesAnnotation],
+		)
+		return err
+	}
+	if len(additionalWriteEndpoints) > 0 {
+		additionalWriteEndpointsYAML, err := yaml.Marshal(additionalWriteEndpoints)
 		if err != nil {
+			log.Error(err, "cannot marshal endpoints of additional regional clusters")
 			return err
 		}
+		configData[AdditionalWriteEndpointsKey] = string(additionalWriteEndpointsYAML)
+	}
 
-		configData[WriteLogsKey], err = getEndpoint(ctx, WriteLogsAnnotation, regionalClusterDeployment, regionalClusterDeploymentConfig)
-		if err != nil {
+	if childCredentialsEnabled(childClusterDeployment) {
+		if _, isIstio := regionalClusterDeployment.Labels[IstioRoleLabel]; isIstio {
+			err := fmt.Errorf("child credentials are not supported with istio regional cluster")
+			utils.LogEvent(
+				ctx,
+				"ChildCredentialsNotSupported",
+				"Credentials of the child cluster are not supported by its regional cluster",
+				childClusterDeployment,
+				err,
+				"annotation", KofChildCredentialsAnnotation,
+				"regionalClusterName", regionalClusterName,
+			)
 			return err
 		}
-
-		configData[WriteTracesKey], err = getEndpoint(ctx, WriteTracesAnnotation, regionalClusterDeployment, regionalClusterDeploymentConfig)
+		secretName, err := r.reconcileChildCredentials(
+			ctx,
+			ownerReference,
+			childClusterDeployment,
+			append([]string{regionalClusterName}, slices.Sorted(maps.Keys(additionalWriteEndpoints))...),
+			endpointTemplates.getStorageServices(),
+			tenant,
+		)
 		if err != nil {
 			return err
 		}
+		configData[CredentialsSecretNameKey] = secretName
+	} else if err := r.deleteChildCredentials(ctx, childClusterDeployment); err != nil {
+		return err
 	}
 
-	configMap = &corev1.ConfigMap{
+	configMap := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            configMapName,
 			Namespace:       childClusterDeployment.Namespace,
@@ -201,14 +534,15 @@ func (r *ClusterDeploymentReconciler) reconcileChildClusterRole(
 		Data: configData,
 	}
 
-	if err := r.createIfNotExists(ctx, configMap, "child cluster ConfigMap", []any{
+	result, err := r.createOrUpdate(ctx, configMap, "child cluster ConfigMap", []any{
 		"configMapName", configMap.Name,
 		"configMapData", configData,
-	}); err != nil {
+	})
+	if err != nil {
 		utils.LogEvent(
 			ctx,
 			"ConfigMapCreationFailed",
-			"Failed to create child cluster ConfigMap",
+			"Failed to create or update child cluster ConfigMap",
 			childClusterDeployment,
 			err,
 			"configMapName", configMap.Name,
@@ -217,15 +551,28 @@ func (r *ClusterDeploymentReconciler) reconcileChildClusterRole(
 		return err
 	}
 
-	utils.LogEvent(
-		ctx,
-		"ConfigMapCreated",
-		"Created child cluster ConfigMap",
-		childClusterDeployment,
-		nil,
-		"configMapName", configMap.Name,
-		"configMapData", configData,
-	)
+	switch result {
+	case controllerutil.OperationResultCreated:
+		utils.LogEvent(
+			ctx,
+			"ConfigMapCreated",
+			"Created child cluster ConfigMap",
+			childClusterDeployment,
+			nil,
+			"configMapName", configMap.Name,
+			"configMapData", configData,
+		)
+	case controllerutil.OperationResultUpdated:
+		utils.LogEvent(
+			ctx,
+			"ConfigMapUpdated",
+			"Updated child cluster ConfigMap",
+			childClusterDeployment,
+			nil,
+			"configMapName", configMap.Name,
+			"configMapData", configData,
+		)
+	}
 	return nil
 }
 
@@ -234,11 +581,8 @@ func (r *ClusterDeploymentReconciler) createProfile(
 	ownerReference metav1.OwnerReference,
 	childClusterDeployment, regionalClusterDeployment *kcmv1beta1.ClusterDeployment,
 ) error {
-	log := log.FromContext(ctx)
 	remoteSecretName := remotesecret.GetRemoteSecretName(regionalClusterDeployment.Name)
 
-	log.Info("Creating profile")
-
 	profile := &sveltosv1beta1.Profile{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            remotesecret.CopyRemoteSecretProfileName(childClusterDeployment.Name),
@@ -268,21 +612,27 @@ func (r *ClusterDeploymentReconciler) createProfile(
 			},
 			PolicyRefs: []sveltosv1beta1.PolicyRef{
 				{
-					Kind:      "ConfigMap",
-					Name:      KofIstioSecretTemplate,
-					Namespace: istio.IstioSystemNamespace,
+					Kind:           "ConfigMap",
+					Name:           KofIstioSecretTemplate,
+					Namespace:      istio.IstioSystemNamespace,
+					DeploymentType: sveltosv1beta1.DeploymentTypeRemote,
 				},
 			},
+			// Defaults of the CRD are set explicitly to avoid updates on each reconciliation.
+			SyncMode:             sveltosv1beta1.SyncModeContinuous,
+			StopMatchingBehavior: sveltosv1beta1.WithdrawPolicies,
+			Tier:                 100,
 		},
 	}
 
-	if err := r.createIfNotExists(ctx, profile, "Profile", []any{
+	result, err := r.createOrUpdate(ctx, profile, "Profile", []any{
 		"profileName", profile.Name,
-	}); err != nil {
+	})
+	if err != nil {
 		utils.LogEvent(
 			ctx,
 			"ProfileCreationFailed",
-			"Failed to create Profile",
+			"Failed to create or update Profile",
 			regionalClusterDeployment,
 			err,
 			"profileName", profile.Name,
@@ -290,18 +640,31 @@ func (r *ClusterDeploymentReconciler) createProfile(
 		return err
 	}
 
-	utils.LogEvent(
-		ctx,
-		"ProfileCreated",
-		"Copy remote secret Profile is successfully created",
-		regionalClusterDeployment,
-		nil,
-		"profileName", profile.Name,
-	)
+	switch result {
+	case controllerutil.OperationResultCreated:
+		utils.LogEvent(
+			ctx,
+			"ProfileCreated",
+			"Copy remote secret Profile is successfully created",
+			regionalClusterDeployment,
+			nil,
+			"profileName", profile.Name,
+		)
+	case controllerutil.OperationResultUpdated:
+		utils.LogEvent(
+			ctx,
+			"ProfileUpdated",
+			"Copy remote secret Profile is successfully updated",
+			regionalClusterDeployment,
+			nil,
+			"profileName", profile.Name,
+		)
+	}
 
 	return nil
 }
 
+// Returns the cloud of the cluster from the prefix of its template, e.g. "aws" for "aws-eks-1-0-0".
 func getCloud(clusterDeployment *kcmv1beta1.ClusterDeployment) string {
 	cloud, _, _ := strings.Cut(clusterDeployment.Spec.Template, "-")
 	return cloud
@@ -311,28 +674,179 @@ func (r *ClusterDeploymentReconciler) discoverRegionalClusterDeploymentByLocatio
 	ctx context.Context,
 	childClusterDeployment *kcmv1beta1.ClusterDeployment,
 ) (*kcmv1beta1.ClusterDeployment, error) {
-	log := log.FromContext(ctx)
-	childCloud := getCloud(childClusterDeployment)
+	regionalClusterDeployments, err := findRegionalClusterDeploymentsByLocation(ctx, r.Client, childClusterDeployment)
+	if err != nil {
+		return nil, err
+	}
 
-	childClusterDeploymentConfig, err := ReadClusterDeploymentConfig(
-		childClusterDeployment.Spec.Config.Raw,
-	)
-	if err != nil || childClusterDeploymentConfig == nil {
-		log.Error(
-			err, "cannot read child ClusterDeployment config",
-			"childClusterDeploymentName", childClusterDeployment.Name,
-		)
+	// The current regional cluster is kept while it matches, so children don't move between regionals.
+	currentRegionalClusterName, err := r.getCurrentRegionalClusterName(ctx, childClusterDeployment)
+	if err != nil {
 		return nil, err
 	}
 
-	regionalClusterDeploymentList := &kcmv1beta1.ClusterDeploymentList{}
-	for {
-		opts := []client.ListOption{client.MatchingLabels{KofClusterRoleLabel: "regional"}}
-		if regionalClusterDeploymentList.Continue != "" {
-			opts = append(opts, client.Continue(regionalClusterDeploymentList.Continue))
-		}
+	if regionalFailoverEnabled(childClusterDeployment) {
+		readyRegionalClusterDeployments := slices.DeleteFunc(
+			slices.Clone(regionalClusterDeployments),
+			func(regionalClusterDeployment kcmv1beta1.ClusterDeployment) bool {
+				return !isClusterDeploymentReady(&regionalClusterDeployment)
+			},
+		)
+		if len(readyRegionalClusterDeployments) > 0 {
+			regionalClusterDeployment := pickRegionalClusterDeployment(
+				readyRegionalClusterDeployments,
+				currentRegionalClusterName,
+			)
+			if currentRegionalClusterName != "" && regionalClusterDeployment.Name != currentRegionalClusterName {
+				utils.LogEvent(
+					ctx,
+					"RegionalClusterFailover",
+					"Child cluster is switched to a ready regional cluster",
+					childClusterDeployment,
+					nil,
+					"previousRegionalClusterName", currentRegionalClusterName,
+					"regionalClusterName", regionalClusterDeployment.Name,
+				)
+			}
+			return regionalClusterDeployment, nil
+		}
+		if len(regionalClusterDeployments) > 0 {
+			utils.LogEvent(
+				ctx,
+				"RegionalClusterFailoverFailed",
+				"No ready regional cluster with matching location is found, keeping the current one",
+				childClusterDeployment,
+				nil,
+				"regionalClusterName", currentRegionalClusterName,
+			)
+		}
+	}
+
+	if len(regionalClusterDeployments) > 0 {
+		return pickRegionalClusterDeployment(regionalClusterDeployments, currentRegionalClusterName), nil
+	}
+
+	err = fmt.Errorf(
+		"regional ClusterDeployment with matching location is not found, "+
+			`please set .metadata.labels["%s"] explicitly or add it to ConfigMap "%s"`,
+		KofRegionalClusterNameLabel,
+		KofRegionalClusterMappingConfigMapName,
+	)
+	utils.LogEvent(
+		ctx,
+		"RegionalClusterDiscoveryFailed",
+		"Failed to discover regional cluster",
+		childClusterDeployment,
+		err,
+		"childClusterDeploymentName", childClusterDeployment.Name,
+	)
+	return nil, err
+}
+
+// Returns the regional cluster name from the existing child cluster ConfigMap, or "" if it is not created yet.
+func (r *ClusterDeploymentReconciler) getCurrentRegionalClusterName(
+	ctx context.Context,
+	childClusterDeployment *kcmv1beta1.ClusterDeployment,
+) (string, error) {
+	configMap := &corev1.ConfigMap{}
+	configMapName := "kof-cluster-config-" + childClusterDeployment.Name
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      configMapName,
+		Namespace: childClusterDeployment.Namespace,
+	}, configMap); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		log.FromContext(ctx).Error(
+			err, "cannot read existing child cluster ConfigMap",
+			"configMapName", configMapName,
+		)
+		return "", err
+	}
+	return configMap.Data[RegionalClusterNameKey], nil
+}
 
-		if err := r.List(ctx, regionalClusterDeploymentList, opts...); err != nil {
+// Returns the regional ClusterDeployment named `currentRegionalClusterName` if it is found, or the first one.
+func pickRegionalClusterDeployment(
+	regionalClusterDeployments []kcmv1beta1.ClusterDeployment,
+	currentRegionalClusterName string,
+) *kcmv1beta1.ClusterDeployment {
+	for i := range regionalClusterDeployments {
+		if regionalClusterDeployments[i].Name == currentRegionalClusterName {
+			return &regionalClusterDeployments[i]
+		}
+	}
+	return &regionalClusterDeployments[0]
+}
+
+// Returns true if the child opted in to failover to another regional cluster with matching location.
+func regionalFailoverEnabled(childClusterDeployment *kcmv1beta1.ClusterDeployment) bool {
+	enabled, err := strconv.ParseBool(childClusterDeployment.Annotations[KofRegionalFailoverAnnotation])
+	return err == nil && enabled
+}
+
+func isClusterDeploymentReady(clusterDeployment *kcmv1beta1.ClusterDeployment) bool {
+	return clusterDeployment.DeletionTimestamp.IsZero() &&
+		meta.IsStatusConditionTrue(clusterDeployment.Status.Conditions, kcmv1beta1.ReadyCondition)
+}
+
+// Returns the first regional ClusterDeployment in the same cloud and location as `childClusterDeployment`,
+// or nil if it is not found.
+func findRegionalClusterDeploymentByLocation(
+	ctx context.Context,
+	reader client.Reader,
+	childClusterDeployment *kcmv1beta1.ClusterDeployment,
+) (*kcmv1beta1.ClusterDeployment, error) {
+	regionalClusterDeployments, err := findRegionalClusterDeploymentsByLocation(ctx, reader, childClusterDeployment)
+	if err != nil || len(regionalClusterDeployments) == 0 {
+		return nil, err
+	}
+	return &regionalClusterDeployments[0], nil
+}
+
+// Returns regional ClusterDeployments mapped to `childClusterDeployment` by `RegionalClusterMapping`,
+// or else the ones in the same cloud and location,
+// sorted by name, so the discovery result does not depend on the order of the list.
+func findRegionalClusterDeploymentsByLocation(
+	ctx context.Context,
+	reader client.Reader,
+	childClusterDeployment *kcmv1beta1.ClusterDeployment,
+) ([]kcmv1beta1.ClusterDeployment, error) {
+	log := log.FromContext(ctx)
+	childCloud := getCloud(childClusterDeployment)
+
+	childClusterDeploymentConfig, err := ReadClusterDeploymentConfig(
+		getConfigRaw(childClusterDeployment),
+	)
+	if err != nil || childClusterDeploymentConfig == nil {
+		log.Error(
+			err, "cannot read child ClusterDeployment config",
+			"childClusterDeploymentName", childClusterDeployment.Name,
+		)
+		return nil, err
+	}
+
+	childLocation, _ := getLocation(childCloud, childClusterDeploymentConfig)
+	mapped, isMapped, err := findMappedRegionalClusterDeployments(
+		ctx,
+		reader,
+		childClusterDeployment,
+		childCloud,
+		childLocation,
+	)
+	if err != nil || isMapped {
+		return mapped, err
+	}
+
+	var found []kcmv1beta1.ClusterDeployment
+	regionalClusterDeploymentList := &kcmv1beta1.ClusterDeploymentList{}
+	for {
+		opts := []client.ListOption{client.MatchingLabels{KofClusterRoleLabel: "regional"}}
+		if regionalClusterDeploymentList.Continue != "" {
+			opts = append(opts, client.Continue(regionalClusterDeploymentList.Continue))
+		}
+
+		if err := reader.List(ctx, regionalClusterDeploymentList, opts...); err != nil {
 			log.Error(err, "cannot list regional ClusterDeployments")
 			return nil, err
 		}
@@ -343,7 +857,7 @@ func (r *ClusterDeploymentReconciler) discoverRegionalClusterDeploymentByLocatio
 			}
 
 			regionalClusterDeploymentConfig, err := ReadClusterDeploymentConfig(
-				regionalClusterDeployment.Spec.Config.Raw,
+				getConfigRaw(&regionalClusterDeployment),
 			)
 			if err != nil {
 				continue
@@ -354,7 +868,7 @@ func (r *ClusterDeploymentReconciler) discoverRegionalClusterDeploymentByLocatio
 				childClusterDeploymentConfig,
 				regionalClusterDeploymentConfig,
 			) {
-				return &regionalClusterDeployment, nil
+				found = append(found, regionalClusterDeployment)
 			}
 		}
 
@@ -363,86 +877,288 @@ func (r *ClusterDeploymentReconciler) discoverRegionalClusterDeploymentByLocatio
 		}
 	}
 
-	err = fmt.Errorf(
-		"regional ClusterDeployment with matching location is not found, "+
-			`please set .metadata.labels["%s"] explicitly`,
-		KofRegionalClusterNameLabel,
-	)
-	utils.LogEvent(
-		ctx,
-		"RegionalClusterDiscoveryFailed",
-		"Failed to discover regional cluster",
-		childClusterDeployment,
-		err,
-		"childClusterDeploymentName", childClusterDeployment.Name,
-	)
-	return nil, err
+	slices.SortFunc(found, func(a, b kcmv1beta1.ClusterDeployment) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return found, nil
+}
+
+// Location getters of clouds detected by `getCloud` from the templates of kcm:
+// `aws-standalone-cp`, `aws-hosted-cp`, `aws-eks`, `azure-standalone-cp`, `azure-hosted-cp`, `azure-aks`,
+// `docker-hosted-cp`, `gcp-standalone-cp`, `gcp-hosted-cp`, `gcp-gke`, `openstack-standalone-cp`,
+// `vsphere-standalone-cp` and `vsphere-hosted-cp`.
+// Clouds without location like `adopted` and `remote` are matched by `RegionalClusterMapping` only.
+var cloudLocations = map[string]func(*ClusterDeploymentConfig) string{
+	"aws":   func(c *ClusterDeploymentConfig) string { return c.Region },
+	"azure": func(c *ClusterDeploymentConfig) string { return c.Location },
+	// All docker clusters are local to the management cluster.
+	"docker":    func(c *ClusterDeploymentConfig) string { return "" },
+	"gcp":       func(c *ClusterDeploymentConfig) string { return c.Region },
+	"openstack": func(c *ClusterDeploymentConfig) string { return c.IdentityRef.Region },
+	"vsphere":   func(c *ClusterDeploymentConfig) string { return c.VSphere.Datacenter },
+}
+
+// Returns the location of the cluster, or false if the cloud has no location.
+func getLocation(cloud string, config *ClusterDeploymentConfig) (string, bool) {
+	getCloudLocation, ok := cloudLocations[cloud]
+	if !ok {
+		return "", false
+	}
+	return getCloudLocation(config), true
 }
 
 func locationIsTheSame(cloud string, c1, c2 *ClusterDeploymentConfig) bool {
-	switch cloud {
-	case "adopted":
-		return false
-	case "aws":
-		return c1.Region == c2.Region
-	case "azure":
-		return c1.Location == c2.Location
-	case "docker":
-		return true
-	case "openstack":
-		return c1.IdentityRef.Region == c2.IdentityRef.Region
-	case "remote":
+	location1, ok := getLocation(cloud, c1)
+	if !ok {
 		return false
-	case "vsphere":
-		return c1.VSphere.Datacenter == c2.VSphere.Datacenter
 	}
-
-	return false
+	location2, _ := getLocation(cloud, c2)
+	return location1 == location2
 }
 
+// Returns the endpoint from the annotation of the regional cluster with the metrics tenant set,
+// or rendered from the template of `endpointTemplates` for the `tenant`.
 func getEndpoint(
 	ctx context.Context,
 	endpointAnnotation string,
 	regionalClusterDeployment *kcmv1beta1.ClusterDeployment,
 	regionalClusterDeploymentConfig *ClusterDeploymentConfig,
+	endpointTemplates *EndpointTemplates,
+	tenant *Tenant,
 ) (string, error) {
 	log := log.FromContext(ctx)
-	regionalClusterName := regionalClusterDeployment.Name
 	_, isIstio := regionalClusterDeployment.Labels[IstioRoleLabel]
 	regionalAnnotations := regionalClusterDeploymentConfig.ClusterAnnotations
-	regionalDomain, hasRegionalDomain := regionalAnnotations[KofRegionalDomainAnnotation]
+	_, hasRegionalDomain := regionalAnnotations[KofRegionalDomainAnnotation]
 
-	endpoint, ok := regionalAnnotations[endpointAnnotation]
-	if !ok {
-		if isIstio {
-			endpoint = fmt.Sprintf(istioEndpoints[endpointAnnotation], regionalClusterName)
-		} else if hasRegionalDomain {
-			endpoint = fmt.Sprintf(defaultEndpoints[endpointAnnotation], regionalDomain)
-		} else {
-			err := fmt.Errorf("neither endpoint nor regional domain is set")
+	if endpoint, ok := regionalAnnotations[endpointAnnotation]; ok {
+		// Literal endpoints have no `TenantID` variable, so the tenant is set in their path.
+		if endpointAnnotation != ReadMetricsAnnotation && endpointAnnotation != WriteMetricsAnnotation {
+			return endpoint, nil
+		}
+		endpoint, err := setMetricsTenant(endpoint, tenant)
+		if err != nil {
 			log.Error(
 				err, "in",
 				"regionalClusterDeploymentName", regionalClusterDeployment.Name,
 				"endpointAnnotation", endpointAnnotation,
-				"regionalDomainAnnotation", KofRegionalDomainAnnotation,
+				"tenant", tenant.Name,
 			)
 			return "", err
 		}
+		return endpoint, nil
+	}
+
+	endpointTemplate := endpointTemplates.get(endpointAnnotation, isIstio)
+	endpoint, err := renderEndpointTemplate(
+		endpointTemplate,
+		getEndpointTemplateData(regionalClusterDeployment, regionalClusterDeploymentConfig, tenant),
+	)
+	if err != nil {
+		if !hasRegionalDomain {
+			// Missing `Domain` variable is the usual cause, as the built-in templates need it.
+			err = fmt.Errorf("neither endpoint nor regional domain is set: %w", err)
+		}
+		log.Error(
+			err, "cannot render endpoint template",
+			"regionalClusterDeploymentName", regionalClusterDeployment.Name,
+			"endpointAnnotation", endpointAnnotation,
+			"endpointTemplate", endpointTemplate,
+			"regionalDomainAnnotation", KofRegionalDomainAnnotation,
+		)
+		return "", err
 	}
 	return endpoint, nil
 }
 
-func (r *ClusterDeploymentReconciler) reconcileRegionalClusterRole(
+// Endpoints of the regional cluster written to the child cluster ConfigMap:
+var childClusterEndpointAnnotations = []struct{ key, annotation string }{
+	{ReadMetricsKey, ReadMetricsAnnotation},
+	{WriteMetricsKey, WriteMetricsAnnotation},
+	{WriteLogsKey, WriteLogsAnnotation},
+	{WriteTracesKey, WriteTracesAnnotation},
+}
+
+// Returns the endpoints of the non-istio `regionalClusterDeployment`
+// by the child cluster ConfigMap data keys, with the metrics endpoints of the `tenant`.
+func getChildClusterEndpoints(
 	ctx context.Context,
 	regionalClusterDeployment *kcmv1beta1.ClusterDeployment,
-) error {
+	endpointTemplates *EndpointTemplates,
+	tenant *Tenant,
+) (map[string]string, error) {
 	log := log.FromContext(ctx)
-	regionalClusterName := regionalClusterDeployment.Name
 
-	releaseNamespace, ok := os.LookupEnv("RELEASE_NAMESPACE")
+	regionalClusterDeploymentConfig, err := ReadClusterDeploymentConfig(
+		getConfigRaw(regionalClusterDeployment),
+	)
+	if err != nil {
+		log.Error(
+			err, "cannot read regional ClusterDeployment config",
+			"regionalClusterDeploymentName", regionalClusterDeployment.Name,
+		)
+		return nil, err
+	}
+
+	endpoints := make(map[string]string, len(childClusterEndpointAnnotations))
+	for _, endpoint := range childClusterEndpointAnnotations {
+		endpoints[endpoint.key], err = getEndpoint(
+			ctx,
+			endpoint.annotation,
+			regionalClusterDeployment,
+			regionalClusterDeploymentConfig,
+			endpointTemplates,
+			tenant,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return endpoints, nil
+}
+
+// CollectorsEndpoint and AdditionalWriteEndpoints follow `kof.additional_endpoints`
+// values of the kof-collectors chart, so the kof-child chart passes them as is.
+type CollectorsEndpoint struct {
+	Endpoint string            `json:"endpoint"`
+	Headers  map[string]string `json:"headers,omitempty"`
+}
+
+type AdditionalWriteEndpoints struct {
+	Metrics CollectorsEndpoint `json:"metrics"`
+	Logs    CollectorsEndpoint `json:"logs"`
+	Traces  CollectorsEndpoint `json:"traces"`
+}
+
+// Returns non-empty unique names from the comma-separated annotation of the child cluster.
+func getAdditionalRegionalClusterNames(childClusterDeployment *kcmv1beta1.ClusterDeployment) []string {
+	return splitCommaSeparated(childClusterDeployment.Annotations[KofAdditionalRegionalClusterNamesAnnotation])
+}
+
+// Returns the optional GrafanaDatasources enabled by the comma-separated annotation of the regional cluster.
+func getGrafanaDatasources(regionalClusterDeployment *kcmv1beta1.ClusterDeployment) ([]string, error) {
+	grafanaDatasources := splitCommaSeparated(regionalClusterDeployment.Annotations[KofGrafanaDatasourcesAnnotation])
+	for _, grafanaDatasource := range grafanaDatasources {
+		if !slices.Contains(optionalGrafanaDatasources, grafanaDatasource) {
+			return nil, fmt.Errorf(
+				"unsupported GrafanaDatasource %q, supported: %s",
+				grafanaDatasource,
+				strings.Join(optionalGrafanaDatasources, ", "),
+			)
+		}
+	}
+	return grafanaDatasources, nil
+}
+
+// Returns non-empty unique trimmed items of the comma-separated `value`.
+func splitCommaSeparated(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" && !slices.Contains(items, item) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Returns the write endpoints of additional regional clusters the child cluster writes to,
+// on top of its primary `regionalClusterName`, by regional cluster name.
+// The endpoints write to the same `tenant` as the primary ones.
+func getAdditionalWriteEndpoints(
+	ctx context.Context,
+	reader client.Reader,
+	childClusterDeployment *kcmv1beta1.ClusterDeployment,
+	regionalClusterName string,
+	endpointTemplates *EndpointTemplates,
+	tenant *Tenant,
+) (map[string]AdditionalWriteEndpoints, error) {
+	names := getAdditionalRegionalClusterNames(childClusterDeployment)
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	additionalWriteEndpoints := make(map[string]AdditionalWriteEndpoints, len(names))
+	for _, name := range names {
+		if name == regionalClusterName {
+			return nil, fmt.Errorf(`"%s" is already the regional cluster of the child`, name)
+		}
+
+		regionalClusterDeployment := &kcmv1beta1.ClusterDeployment{}
+		if err := reader.Get(ctx, types.NamespacedName{
+			Name:      name,
+			Namespace: childClusterDeployment.Namespace,
+		}, regionalClusterDeployment); err != nil {
+			return nil, fmt.Errorf(`cannot get regional ClusterDeployment "%s": %w`, name, err)
+		}
+		if regionalClusterDeployment.Labels[KofClusterRoleLabel] != "regional" {
+			return nil, fmt.Errorf(
+				`ClusterDeployment "%s" does not have label "%s: regional"`,
+				name, KofClusterRoleLabel,
+			)
+		}
+		if _, isIstio := regionalClusterDeployment.Labels[IstioRoleLabel]; isIstio {
+			return nil, fmt.Errorf(`istio regional ClusterDeployment "%s" is not supported as additional`, name)
+		}
+
+		endpoints, err := getChildClusterEndpoints(ctx, regionalClusterDeployment, endpointTemplates, tenant)
+		if err != nil {
+			return nil, fmt.Errorf(`cannot get endpoints of regional ClusterDeployment "%s": %w`, name, err)
+		}
+		writeEndpoints := AdditionalWriteEndpoints{
+			Metrics: CollectorsEndpoint{Endpoint: endpoints[WriteMetricsKey]},
+			Logs:    CollectorsEndpoint{Endpoint: endpoints[WriteLogsKey]},
+			Traces:  CollectorsEndpoint{Endpoint: endpoints[WriteTracesKey]},
+		}
+		if tenant != nil {
+			writeEndpoints.Logs.Headers = tenant.logsHeaders()
+		}
+		additionalWriteEndpoints[name] = writeEndpoints
+	}
+	return additionalWriteEndpoints, nil
+}
+
+// Returns the explicit port of `endpointURL` or the default port of its scheme.
+func getEndpointPort(endpointURL *url.URL) (string, error) {
+	if port := endpointURL.Port(); port != "" {
+		return port, nil
+	}
+	switch endpointURL.Scheme {
+	case "http":
+		return "80", nil
+	case "https":
+		return "443", nil
+	}
+	return "", fmt.Errorf("cannot detect port of endpoint %q", endpointURL.String())
+}
+
+// Returns the validated http client config from the annotation of `regionalClusterDeployment`,
+// or nil if the annotation is not set.
+func getRegionalHTTPClientConfig(
+	regionalClusterDeployment *kcmv1beta1.ClusterDeployment,
+) (*kofv1beta1.HTTPClientConfig, error) {
+	httpConfigJson, ok := regionalClusterDeployment.Annotations[KofRegionalHTTPClientConfigAnnotation]
 	if !ok {
-		return fmt.Errorf("required RELEASE_NAMESPACE env var is not set")
+		return nil, nil
+	}
+	httpClientConfig := &kofv1beta1.HTTPClientConfig{
+		DialTimeout: defaultDialTimeout,
+	}
+	if err := json.Unmarshal([]byte(httpConfigJson), httpClientConfig); err != nil {
+		return nil, err
+	}
+	if err := validateHTTPClientConfig(httpClientConfig); err != nil {
+		return nil, err
 	}
+	return httpClientConfig, nil
+}
+
+func (r *ClusterDeploymentReconciler) reconcileRegionalClusterRole(
+	ctx context.Context,
+	regionalClusterDeployment *kcmv1beta1.ClusterDeployment,
+) error {
+	log := log.FromContext(ctx)
+	regionalClusterName := regionalClusterDeployment.Name
 
 	ownerReference, err := utils.GetOwnerReference(regionalClusterDeployment, r.Client)
 	if err != nil {
@@ -470,44 +1186,154 @@ func (r *ClusterDeploymentReconciler) reconcileRegionalClusterRole(
 		return err
 	}
 
-	grafanaDatasource := &grafanav1beta1.GrafanaDatasource{}
-	grafanaDatasourceName := regionalClusterName + "-logs"
-	err = r.Get(ctx, types.NamespacedName{
-		Name:      grafanaDatasourceName,
-		Namespace: releaseNamespace,
-	}, grafanaDatasource)
-	if err != nil && !errors.IsNotFound(err) {
+	regionalClusterDeploymentConfig, err := ReadClusterDeploymentConfig(
+		getConfigRaw(regionalClusterDeployment),
+	)
+	if err != nil {
 		log.Error(
-			err, "cannot read existing GrafanaDatasource",
-			"grafanaDatasourceName", grafanaDatasourceName,
+			err, "cannot read regional ClusterDeployment config",
+			"regionalClusterDeploymentName", regionalClusterDeployment.Name,
 		)
 		return err
 	}
-	if err == nil {
-		log.Info(
-			"Found existing regional objects",
-			"grafanaDatasourceName", grafanaDatasourceName,
+
+	httpClientConfig, err := getRegionalHTTPClientConfig(regionalClusterDeployment)
+	if err != nil {
+		utils.LogEvent(
+			ctx,
+			"InvalidRegionalHTTPClientConfigAnnotation",
+			"Failed to parse JSON from annotation",
+			regionalClusterDeployment,
+			err,
+			"annotation", KofRegionalHTTPClientConfigAnnotation,
+			"value", regionalClusterDeployment.Annotations[KofRegionalHTTPClientConfigAnnotation],
 		)
-		return nil
+		return err
 	}
 
-	regionalClusterDeploymentConfig, err := ReadClusterDeploymentConfig(
-		regionalClusterDeployment.Spec.Config.Raw,
-	)
+	grafanaDatasources, err := getGrafanaDatasources(regionalClusterDeployment)
 	if err != nil {
-		log.Error(
-			err, "cannot read regional ClusterDeployment config",
-			"regionalClusterDeploymentName", regionalClusterDeployment.Name,
+		utils.LogEvent(
+			ctx,
+			"InvalidGrafanaDatasourcesAnnotation",
+			"Failed to parse optional GrafanaDatasources from annotation",
+			regionalClusterDeployment,
+			err,
+			"annotation", KofGrafanaDatasourcesAnnotation,
+			"value", regionalClusterDeployment.Annotations[KofGrafanaDatasourcesAnnotation],
 		)
 		return err
 	}
 
-	logsEndpoint, err := getEndpoint(ctx, ReadLogsAnnotation, regionalClusterDeployment, regionalClusterDeploymentConfig)
+	tenants, err := readTenants(ctx, r.Client)
 	if err != nil {
+		utils.LogEvent(
+			ctx,
+			"InvalidTenantMapping",
+			"Failed to read tenants",
+			regionalClusterDeployment,
+			err,
+			"configMapName", KofTenantMappingConfigMapName,
+		)
 		return err
 	}
 
-	metricsEndpoint, err := getEndpoint(ctx, ReadMetricsAnnotation, regionalClusterDeployment, regionalClusterDeploymentConfig)
+	endpointTemplates, err := readEndpointTemplates(ctx, r.Client)
+	if err != nil {
+		utils.LogEvent(
+			ctx,
+			"InvalidEndpointTemplates",
+			"Failed to read endpoint templates",
+			regionalClusterDeployment,
+			err,
+			"configMapName", KofEndpointTemplatesConfigMapName,
+		)
+		return err
+	}
+
+	// The default tenant first, then the tenants sharing the regional cluster.
+	if err := r.reconcileRegionalTenant(
+		ctx,
+		regionalClusterDeployment,
+		regionalClusterDeploymentConfig,
+		endpointTemplates,
+		nil,
+		httpClientConfig,
+		grafanaDatasources,
+	); err != nil {
+		return err
+	}
+	for _, tenant := range tenants {
+		if err := r.reconcileRegionalTenant(
+			ctx,
+			regionalClusterDeployment,
+			regionalClusterDeploymentConfig,
+			endpointTemplates,
+			&tenant,
+			httpClientConfig,
+			grafanaDatasources,
+		); err != nil {
+			return err
+		}
+	}
+	return r.deleteRemovedTenantObjects(ctx, regionalClusterDeployment, tenants)
+}
+
+// Creates or updates the PromxyServerGroup and GrafanaDatasources of the regional cluster
+// reading the data of the `tenant`, or of the default tenant if it is nil.
+// The objects of a tenant have its name suffix and `KofTenantLabel`,
+// its PromxyServerGroup shares the promxy config Secret and adds `TenantSeriesLabel` to its series.
+func (r *ClusterDeploymentReconciler) reconcileRegionalTenant(
+	ctx context.Context,
+	regionalClusterDeployment *kcmv1beta1.ClusterDeployment,
+	regionalClusterDeploymentConfig *ClusterDeploymentConfig,
+	endpointTemplates *EndpointTemplates,
+	tenant *Tenant,
+	httpClientConfig *kofv1beta1.HTTPClientConfig,
+	grafanaDatasources []string,
+) error {
+	log := log.FromContext(ctx)
+	regionalClusterName := regionalClusterDeployment.Name
+
+	releaseNamespace, ok := os.LookupEnv("RELEASE_NAMESPACE")
+	if !ok {
+		return fmt.Errorf("required RELEASE_NAMESPACE env var is not set")
+	}
+
+	nameSuffix := ""
+	labels := map[string]string{utils.ManagedByLabel: utils.ManagedByValue}
+	promxySecretName := GetServedPromxySecretName()
+	var seriesLabels map[string]string
+	var logsHeaders map[string]string
+	if tenant != nil {
+		nameSuffix = "-" + tenant.Name
+		labels[KofTenantLabel] = tenant.Name
+		labels[KofRegionalClusterNameLabel] = regionalClusterName
+		promxySecretName = tenant.promxySecretName()
+		seriesLabels = map[string]string{TenantSeriesLabel: tenant.Name}
+		logsHeaders = tenant.logsHeaders()
+	}
+
+	logsEndpoint, err := getEndpoint(
+		ctx,
+		ReadLogsAnnotation,
+		regionalClusterDeployment,
+		regionalClusterDeploymentConfig,
+		endpointTemplates,
+		tenant,
+	)
+	if err != nil {
+		return err
+	}
+
+	metricsEndpoint, err := getEndpoint(
+		ctx,
+		ReadMetricsAnnotation,
+		regionalClusterDeployment,
+		regionalClusterDeploymentConfig,
+		endpointTemplates,
+		tenant,
+	)
 	if err != nil {
 		return err
 	}
@@ -523,82 +1349,63 @@ func (r *ClusterDeploymentReconciler) reconcileRegionalClusterRole(
 		return err
 	}
 
-	metricsPort := metricsURL.Port()
-	if metricsPort == "" {
-		switch metricsURL.Scheme {
-		case "http":
-			metricsPort = "80"
-		case "https":
-			metricsPort = "443"
-		default:
-			err := fmt.Errorf("cannot detect port of metrics endpoint")
-			log.Error(
-				err, "in",
-				"regionalClusterDeploymentName", regionalClusterDeployment.Name,
-				"metricsEndpointAnnotation", ReadMetricsAnnotation,
-				"metricsEndpointValue", metricsEndpoint,
-			)
-			return err
-		}
+	metricsPort, err := getEndpointPort(metricsURL)
+	if err != nil {
+		log.Error(
+			err, "in",
+			"regionalClusterDeploymentName", regionalClusterDeployment.Name,
+			"metricsEndpointAnnotation", ReadMetricsAnnotation,
+			"metricsEndpointValue", metricsEndpoint,
+		)
+		return err
 	}
 
 	metricsTarget := fmt.Sprintf("%s:%s", metricsURL.Hostname(), metricsPort)
 	_, isIstio := regionalClusterDeployment.Labels[IstioRoleLabel]
 
+	promxyServerGroupLabels := maps.Clone(labels)
+	promxyServerGroupLabels[PromxySecretNameLabel] = promxySecretName
 	promxyServerGroup := &kofv1beta1.PromxyServerGroup{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      regionalClusterName + "-metrics",
+			Name:      regionalClusterName + "-metrics" + nameSuffix,
 			Namespace: releaseNamespace,
 			// `OwnerReferences` is N/A because `regionalClusterDeployment` namespace differs.
-			Labels: map[string]string{
-				utils.ManagedByLabel:  utils.ManagedByValue,
-				PromxySecretNameLabel: "kof-mothership-promxy-config",
-			},
+			Labels: promxyServerGroupLabels,
 		},
 		Spec: kofv1beta1.PromxyServerGroupSpec{
 			ClusterName: regionalClusterName,
 			Scheme:      metricsURL.Scheme,
 			Targets:     []string{metricsTarget},
 			PathPrefix:  metricsURL.EscapedPath(),
+			Labels:      seriesLabels,
 			HttpClient: kofv1beta1.HTTPClientConfig{
 				DialTimeout: defaultDialTimeout,
 			},
 		},
 	}
 
-	var httpClientConfig *kofv1beta1.HTTPClientConfig
-	if httpConfigJson, ok := regionalClusterDeployment.Annotations[KofRegionalHTTPClientConfigAnnotation]; ok {
-		httpClientConfig = &kofv1beta1.HTTPClientConfig{
-			DialTimeout: defaultDialTimeout,
-		}
-		if err := json.Unmarshal([]byte(httpConfigJson), httpClientConfig); err != nil {
-			utils.LogEvent(
-				ctx,
-				"InvalidRegionalHTTPClientConfigAnnotation",
-				"Failed to parse JSON from annotation",
-				regionalClusterDeployment,
-				err,
-				"annotation", KofRegionalHTTPClientConfigAnnotation,
-				"value", httpConfigJson,
-			)
-			return err
-		}
+	if httpClientConfig != nil {
 		promxyServerGroup.Spec.HttpClient = *httpClientConfig
 	}
-	if !isIstio {
+	// Basic auth with vmuser credentials is the default unless another auth is configured.
+	useBasicAuth := !isIstio &&
+		promxyServerGroup.Spec.HttpClient.BearerToken == nil &&
+		promxyServerGroup.Spec.HttpClient.OAuth2 == nil
+	if useBasicAuth {
 		basicAuth := &promxyServerGroup.Spec.HttpClient.BasicAuth
 		basicAuth.CredentialsSecretName = KofStorageSecretName
 		basicAuth.UsernameKey = "username"
 		basicAuth.PasswordKey = "password"
 	}
 
-	if err := r.createIfNotExists(ctx, promxyServerGroup, "PromxyServerGroup", []any{
+	result, err := r.createOrUpdate(ctx, promxyServerGroup, "PromxyServerGroup", []any{
 		"promxyServerGroupName", promxyServerGroup.Name,
-	}); err != nil {
+	})
+	if err != nil {
 		utils.LogEvent(
 			ctx,
 			"PromxySeverGroupCreationFailed",
-			"Failed to create PromxyServerGroup",
+			"Failed to create or update PromxyServerGroup",
 			regionalClusterDeployment,
 			err,
 			"promxyServerGroupName", promxyServerGroup.Name,
@@ -606,21 +1413,197 @@ func (r *ClusterDeploymentReconciler) reconcileRegionalClusterRole(
 		return err
 	}
 
-	utils.LogEvent(
+	switch result {
+	case controllerutil.OperationResultCreated:
+		utils.LogEvent(
+			ctx,
+			"PromxyServerGroupCreated",
+			"PromxyServerGroup is successfully created",
+			regionalClusterDeployment,
+			nil,
+			"promxyServerGroupName", promxyServerGroup.Name,
+		)
+	case controllerutil.OperationResultUpdated:
+		utils.LogEvent(
+			ctx,
+			"PromxyServerGroupUpdated",
+			"PromxyServerGroup is successfully updated",
+			regionalClusterDeployment,
+			nil,
+			"promxyServerGroupName", promxyServerGroup.Name,
+		)
+	}
+
+	if err := r.reconcileRegionalGrafanaDatasource(
 		ctx,
-		"PromxyServerGroupCreated",
-		"PromxyServerGroup is successfully created",
 		regionalClusterDeployment,
-		nil,
-		"promxyServerGroupName", promxyServerGroup.Name,
-	)
+		regionalClusterName+"-logs"+nameSuffix,
+		labels,
+		&grafanav1beta1.GrafanaDatasourceInternal{
+			Name: regionalClusterName + nameSuffix,
+			Type: "victoriametrics-logs-datasource",
+			URL:  logsEndpoint,
+		},
+		httpClientConfig,
+		useBasicAuth,
+		logsHeaders,
+	); err != nil {
+		return err
+	}
+
+	// Optional datasources query the regional cluster directly, e.g. to debug one region without promxy.
+	optionalDatasources := []struct {
+		name               string
+		datasourceType     string
+		endpointAnnotation string
+	}{
+		{"metrics", "prometheus", ReadMetricsAnnotation},
+		{"traces", "jaeger", ReadTracesAnnotation},
+	}
+	for _, optional := range optionalDatasources {
+		grafanaDatasourceName := regionalClusterName + "-" + optional.name + nameSuffix
+		// Traces are not isolated by tenants, so only the default tenant has the traces datasource.
+		enabled := slices.Contains(grafanaDatasources, optional.name) &&
+			(tenant == nil || optional.name != "traces")
+		if !enabled {
+			if err := r.deleteIfManaged(ctx, &grafanav1beta1.GrafanaDatasource{ObjectMeta: metav1.ObjectMeta{
+				Name:      grafanaDatasourceName,
+				Namespace: releaseNamespace,
+			}}, "GrafanaDatasource"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		endpoint := metricsEndpoint
+		if optional.endpointAnnotation != ReadMetricsAnnotation {
+			endpoint, err = getEndpoint(
+				ctx,
+				optional.endpointAnnotation,
+				regionalClusterDeployment,
+				regionalClusterDeploymentConfig,
+				endpointTemplates,
+				tenant,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		if err := r.reconcileRegionalGrafanaDatasource(
+			ctx,
+			regionalClusterDeployment,
+			grafanaDatasourceName,
+			labels,
+			&grafanav1beta1.GrafanaDatasourceInternal{
+				Name: grafanaDatasourceName,
+				Type: optional.datasourceType,
+				URL:  endpoint,
+			},
+			httpClientConfig,
+			useBasicAuth,
+			nil,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Deletes the PromxyServerGroups and GrafanaDatasources of the regional cluster
+// generated for the tenants which are not in `tenants` anymore.
+func (r *ClusterDeploymentReconciler) deleteRemovedTenantObjects(
+	ctx context.Context,
+	regionalClusterDeployment *kcmv1beta1.ClusterDeployment,
+	tenants []Tenant,
+) error {
+	log := log.FromContext(ctx)
+
+	releaseNamespace, ok := os.LookupEnv("RELEASE_NAMESPACE")
+	if !ok {
+		return fmt.Errorf("required RELEASE_NAMESPACE env var is not set")
+	}
+
+	listOptions := []client.ListOption{
+		client.InNamespace(releaseNamespace),
+		client.MatchingLabels{
+			KofRegionalClusterNameLabel: regionalClusterDeployment.Name,
+			utils.ManagedByLabel:        utils.ManagedByValue,
+		},
+		client.HasLabels{KofTenantLabel},
+	}
+	promxyServerGroupList := &kofv1beta1.PromxyServerGroupList{}
+	if err := r.List(ctx, promxyServerGroupList, listOptions...); err != nil {
+		log.Error(err, "cannot list PromxyServerGroups of tenants")
+		return err
+	}
+	grafanaDatasourceList := &grafanav1beta1.GrafanaDatasourceList{}
+	if err := r.List(ctx, grafanaDatasourceList, listOptions...); err != nil {
+		log.Error(err, "cannot list GrafanaDatasources of tenants")
+		return err
+	}
+
+	type tenantObject struct {
+		object            client.Object
+		objectDescription string
+	}
+	var objects []tenantObject
+	for i := range promxyServerGroupList.Items {
+		objects = append(objects, tenantObject{&promxyServerGroupList.Items[i], "PromxyServerGroup"})
+	}
+	for i := range grafanaDatasourceList.Items {
+		objects = append(objects, tenantObject{&grafanaDatasourceList.Items[i], "GrafanaDatasource"})
+	}
 
-	grafanaDatasource = &grafanav1beta1.GrafanaDatasource{
+	for _, item := range objects {
+		tenantName := item.object.GetLabels()[KofTenantLabel]
+		if slices.ContainsFunc(tenants, func(tenant Tenant) bool { return tenant.Name == tenantName }) {
+			continue
+		}
+		if err := r.deleteIfManaged(ctx, item.object, item.objectDescription); err != nil {
+			utils.LogEvent(
+				ctx,
+				"RegionalObjectDeletionFailed",
+				"Failed to delete "+item.objectDescription+" of removed tenant",
+				regionalClusterDeployment,
+				err,
+				"objectName", item.object.GetName(),
+				"tenant", tenantName,
+			)
+			return err
+		}
+	}
+	return nil
+}
+
+// Creates or updates the GrafanaDatasource of the regional cluster in the release namespace,
+// with the same auth and TLS as the PromxyServerGroup of the regional cluster.
+func (r *ClusterDeploymentReconciler) reconcileRegionalGrafanaDatasource(
+	ctx context.Context,
+	regionalClusterDeployment *kcmv1beta1.ClusterDeployment,
+	grafanaDatasourceName string,
+	labels map[string]string,
+	datasource *grafanav1beta1.GrafanaDatasourceInternal,
+	httpClientConfig *kofv1beta1.HTTPClientConfig,
+	useBasicAuth bool,
+	headers map[string]string,
+) error {
+	log := log.FromContext(ctx)
+
+	releaseNamespace, ok := os.LookupEnv("RELEASE_NAMESPACE")
+	if !ok {
+		return fmt.Errorf("required RELEASE_NAMESPACE env var is not set")
+	}
+
+	datasource.Access = "proxy"
+	datasource.IsDefault = utils.BoolPtr(false)
+	datasource.BasicAuth = utils.BoolPtr(useBasicAuth)
+	grafanaDatasource := &grafanav1beta1.GrafanaDatasource{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      grafanaDatasourceName,
 			Namespace: releaseNamespace,
 			// `OwnerReferences` is N/A because `regionalClusterDeployment` namespace differs.
-			Labels: map[string]string{utils.ManagedByLabel: utils.ManagedByValue},
+			Labels: labels,
 		},
 		Spec: grafanav1beta1.GrafanaDatasourceSpec{
 			GrafanaCommonSpec: grafanav1beta1.GrafanaCommonSpec{
@@ -629,59 +1612,44 @@ func (r *ClusterDeploymentReconciler) reconcileRegionalClusterRole(
 				},
 				ResyncPeriod: metav1.Duration{Duration: 5 * time.Minute},
 			},
-			Datasource: &grafanav1beta1.GrafanaDatasourceInternal{
-				Name:      regionalClusterName,
-				Type:      "victoriametrics-logs-datasource",
-				URL:       logsEndpoint,
-				Access:    "proxy",
-				IsDefault: utils.BoolPtr(false),
-				BasicAuth: utils.BoolPtr(!isIstio),
-			},
+			Datasource: datasource,
 		},
 	}
+	grafanaDatasourceSettings := NewGrafanaDatasourceSettings()
 	if httpClientConfig != nil {
-		grafanaDatasource.Spec.Datasource.JSONData = json.RawMessage(
-			fmt.Sprintf(`{"tlsSkipVerify": %t, "timeout": "%d"}`, httpClientConfig.TLSConfig.InsecureSkipVerify, int(httpClientConfig.DialTimeout.Duration.Seconds())),
-		)
-	}
-	if !isIstio {
-		grafanaDatasource.Spec.Datasource.BasicAuthUser = "${username}" // Set in `ValuesFrom`.
-		grafanaDatasource.Spec.Datasource.SecureJSONData = json.RawMessage(
-			`{"basicAuthPassword": "${password}"}`,
-		)
-		grafanaDatasource.Spec.ValuesFrom = []grafanav1beta1.ValueFrom{
-			{
-				TargetPath: "basicAuthUser",
-				ValueFrom: grafanav1beta1.ValueFromSource{
-					SecretKeyRef: &corev1.SecretKeySelector{
-						LocalObjectReference: corev1.LocalObjectReference{
-							Name: KofStorageSecretName,
-						},
-						Key: "username",
-					},
-				},
-			},
-			{
-				TargetPath: "secureJsonData.basicAuthPassword",
-				ValueFrom: grafanav1beta1.ValueFromSource{
-					SecretKeyRef: &corev1.SecretKeySelector{
-						LocalObjectReference: corev1.LocalObjectReference{
-							Name: KofStorageSecretName,
-						},
-						Key: "password",
-					},
-				},
-			},
+		grafanaDatasourceSettings.SetHTTPClientConfig(httpClientConfig)
+		if !grafanaDatasourceSettings.SetAuth(httpClientConfig) {
+			utils.LogEvent(
+				ctx,
+				"GrafanaDatasourceAuthNotSupported",
+				"Auth of the regional cluster is not supported by GrafanaDatasource, configure it manually",
+				regionalClusterDeployment,
+				nil,
+				"grafanaDatasourceName", grafanaDatasource.Name,
+				"annotation", KofRegionalHTTPClientConfigAnnotation,
+			)
 		}
 	}
+	if useBasicAuth {
+		grafanaDatasourceSettings.SetBasicAuth(grafanaDatasource, KofStorageSecretName)
+	}
+	grafanaDatasourceSettings.SetHeaders(headers)
+	if err := grafanaDatasourceSettings.Apply(grafanaDatasource); err != nil {
+		log.Error(
+			err, "cannot build GrafanaDatasource settings",
+			"grafanaDatasourceName", grafanaDatasource.Name,
+		)
+		return err
+	}
 
-	if err := r.createIfNotExists(ctx, grafanaDatasource, "GrafanaDatasource", []any{
+	result, err := r.createOrUpdate(ctx, grafanaDatasource, "GrafanaDatasource", []any{
 		"grafanaDatasourceName", grafanaDatasource.Name,
-	}); err != nil {
+	})
+	if err != nil {
 		utils.LogEvent(
 			ctx,
 			"GrafanaDatasourceCreationFailed",
-			"Failed to create GrafanaDatasource",
+			"Failed to create or update GrafanaDatasource",
 			regionalClusterDeployment,
 			err,
 			"grafanaDatasourceName", grafanaDatasource.Name,
@@ -689,14 +1657,26 @@ func (r *ClusterDeploymentReconciler) reconcileRegionalClusterRole(
 		return err
 	}
 
-	utils.LogEvent(
-		ctx,
-		"GrafanaDatasourceCreated",
-		"GrafanaDatasource is successfully created",
-		regionalClusterDeployment,
-		nil,
-		"grafanaDatasourceName", grafanaDatasource.Name,
-	)
+	switch result {
+	case controllerutil.OperationResultCreated:
+		utils.LogEvent(
+			ctx,
+			"GrafanaDatasourceCreated",
+			"GrafanaDatasource is successfully created",
+			regionalClusterDeployment,
+			nil,
+			"grafanaDatasourceName", grafanaDatasource.Name,
+		)
+	case controllerutil.OperationResultUpdated:
+		utils.LogEvent(
+			ctx,
+			"GrafanaDatasourceUpdated",
+			"GrafanaDatasource is successfully updated",
+			regionalClusterDeployment,
+			nil,
+			"grafanaDatasourceName", grafanaDatasource.Name,
+		)
+	}
 
 	return nil
 }
@@ -709,9 +1689,8 @@ func (r *ClusterDeploymentReconciler) createIfNotExists(
 ) error {
 	log := log.FromContext(ctx)
 
-	// `createOrUpdate` would need to read an old version and merge it with the new version
-	// to avoid `metadata.resourceVersion: Invalid value: 0x0: must be specified for an update`.
-	// As we have immutable specs for now, we will use `createIfNotExists` instead.
+	// Used for objects which users may edit after creation, e.g. `VMRulesConfigMap`.
+	// Objects derived from the ClusterDeployment config should use `createOrUpdate`.
 
 	if err := r.Create(ctx, object); err != nil {
 		if errors.IsAlreadyExists(err) {
@@ -726,3 +1705,107 @@ func (r *ClusterDeploymentReconciler) createIfNotExists(
 	log.Info("Created "+objectDescription, details...)
 	return nil
 }
+
+// Creates `object` or updates the existing one to the desired `Data` or `Spec` of `object`,
+// so the generated objects converge when the config of ClusterDeployment changes.
+// Labels and owner references of `object` are added to the existing ones, other metadata is kept.
+// Objects without the `utils.ManagedByLabel` are not updated, as they are created by users.
+func (r *ClusterDeploymentReconciler) createOrUpdate(
+	ctx context.Context,
+	object client.Object,
+	objectDescription string,
+	details []any,
+) (controllerutil.OperationResult, error) {
+	log := log.FromContext(ctx)
+
+	desired := object.DeepCopyObject().(client.Object)
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, object, func() error {
+		if object.GetResourceVersion() != "" &&
+			object.GetLabels()[utils.ManagedByLabel] != utils.ManagedByValue {
+			return nil
+		}
+		return setDesiredState(object, desired)
+	})
+	if err != nil {
+		log.Error(err, "cannot create or update "+objectDescription, details...)
+		return result, err
+	}
+
+	switch result {
+	case controllerutil.OperationResultCreated:
+		log.Info("Created "+objectDescription, details...)
+	case controllerutil.OperationResultUpdated:
+		log.Info("Updated "+objectDescription, details...)
+	}
+	// Logging nothing for unchanged objects as we have a lot of frequent `status` updates here.
+	// Cannot add `WithEventFilter(predicate.GenerationChangedPredicate{})`
+	// to `SetupWithManager` of reconciler shared with istio which needs `status` updates.
+	return result, nil
+}
+
+// Copies the labels, the owner references and the `Data` or `Spec` of `desired` to `object`.
+func setDesiredState(object client.Object, desired client.Object) error {
+	labels := object.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	maps.Copy(labels, desired.GetLabels())
+	object.SetLabels(labels)
+
+	ownerReferences := object.GetOwnerReferences()
+	for _, desiredReference := range desired.GetOwnerReferences() {
+		if !slices.ContainsFunc(ownerReferences, func(reference metav1.OwnerReference) bool {
+			return reference.UID == desiredReference.UID
+		}) {
+			ownerReferences = append(ownerReferences, desiredReference)
+		}
+	}
+	object.SetOwnerReferences(ownerReferences)
+
+	switch object := object.(type) {
+	case *corev1.ConfigMap:
+		object.Data = desired.(*corev1.ConfigMap).Data
+	case *kofv1beta1.PromxyServerGroup:
+		object.Spec = desired.(*kofv1beta1.PromxyServerGroup).Spec
+	case *grafanav1beta1.GrafanaDatasource:
+		object.Spec = desired.(*grafanav1beta1.GrafanaDatasource).Spec
+	case *sveltosv1beta1.Profile:
+		object.Spec = desired.(*sveltosv1beta1.Profile).Spec
+	case *kofv1beta1.KofCluster:
+		// KofCluster has status only, it is patched by `reconcileKofCluster`.
+	default:
+		return fmt.Errorf("cannot update unsupported object type %T", object)
+	}
+	return nil
+}
+
+// Deletes `object` if it exists and has the `utils.ManagedByLabel`,
+// so objects created by users with the same name are kept.
+func (r *ClusterDeploymentReconciler) deleteIfManaged(
+	ctx context.Context,
+	object client.Object,
+	objectDescription string,
+) error {
+	log := log.FromContext(ctx)
+	details := []any{"name", object.GetName(), "namespace", object.GetNamespace()}
+
+	if err := r.Get(ctx, client.ObjectKeyFromObject(object), object); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		log.Error(err, "cannot read "+objectDescription, details...)
+		return err
+	}
+
+	if object.GetLabels()[utils.ManagedByLabel] != utils.ManagedByValue {
+		log.Info("Keeping "+objectDescription+" not managed by kof-operator", details...)
+		return nil
+	}
+
+	if err := r.Delete(ctx, object); client.IgnoreNotFound(err) != nil {
+		log.Error(err, "cannot delete "+objectDescription, details...)
+		return err
+	}
+	log.Info("Deleted "+objectDescription, details...)
+	return nil
+}
@@ -0,0 +1,380 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+
+	kcmv1beta1 "github.com/K0rdent/kcm/api/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var kofClusterRoles = []string{"child", "regional"}
+
+// ClusterDeploymentWebhook rejects kof labels and annotations of ClusterDeployment
+// that would fail `ReconcileKofClusterRole` after the cluster is provisioned.
+type ClusterDeploymentWebhook struct {
+	// Reader should not be cached, so regional clusters applied right before children are found.
+	Reader client.Reader
+}
+
+// +kubebuilder:webhook:path=/validate-k0rdent-mirantis-com-v1beta1-clusterdeployment,mutating=false,failurePolicy=fail,sideEffects=None,groups=k0rdent.mirantis.com,resources=clusterdeployments,verbs=create;update,versions=v1beta1,name=vclusterdeployment-v1beta1.kb.io,admissionReviewVersions=v1
+
+// SetupWebhookWithManager registers the webhook in the manager.
+func (w *ClusterDeploymentWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&kcmv1beta1.ClusterDeployment{}).
+		WithValidator(w).
+		Complete()
+}
+
+// ValidateCreate implements admission.CustomValidator.
+func (w *ClusterDeploymentWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	clusterDeployment, ok := obj.(*kcmv1beta1.ClusterDeployment)
+	if !ok {
+		return nil, fmt.Errorf("expected a ClusterDeployment but got a %T", obj)
+	}
+	return nil, w.validate(ctx, clusterDeployment)
+}
+
+// ValidateUpdate implements admission.CustomValidator.
+// Updates not changing the kof config are allowed, so existing clusters are not blocked.
+func (w *ClusterDeploymentWebhook) ValidateUpdate(
+	ctx context.Context,
+	oldObj runtime.Object,
+	newObj runtime.Object,
+) (admission.Warnings, error) {
+	oldClusterDeployment, ok := oldObj.(*kcmv1beta1.ClusterDeployment)
+	if !ok {
+		return nil, fmt.Errorf("expected a ClusterDeployment but got a %T", oldObj)
+	}
+	clusterDeployment, ok := newObj.(*kcmv1beta1.ClusterDeployment)
+	if !ok {
+		return nil, fmt.Errorf("expected a ClusterDeployment but got a %T", newObj)
+	}
+	if !clusterDeployment.DeletionTimestamp.IsZero() || !kofConfigChanged(oldClusterDeployment, clusterDeployment) {
+		return nil, nil
+	}
+	return nil, w.validate(ctx, clusterDeployment)
+}
+
+// ValidateDelete implements admission.CustomValidator, deletion is always allowed.
+func (w *ClusterDeploymentWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (w *ClusterDeploymentWebhook) validate(
+	ctx context.Context,
+	clusterDeployment *kcmv1beta1.ClusterDeployment,
+) error {
+	role, ok := clusterDeployment.Labels[KofClusterRoleLabel]
+	if !ok {
+		return nil
+	}
+
+	endpointTemplates, err := readEndpointTemplates(ctx, w.Reader)
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+
+	rolePath := field.NewPath("metadata", "labels").Key(KofClusterRoleLabel)
+	var errs field.ErrorList
+	switch role {
+	case "child":
+		errs, err = w.validateChild(ctx, clusterDeployment, endpointTemplates)
+	case "regional":
+		errs = validateRegional(ctx, clusterDeployment, endpointTemplates)
+	default:
+		errs = field.ErrorList{field.NotSupported(rolePath, role, kofClusterRoles)}
+	}
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(
+		kcmv1beta1.GroupVersion.WithKind(kcmv1beta1.ClusterDeploymentKind).GroupKind(),
+		clusterDeployment.Name,
+		errs,
+	)
+}
+
+// Checks the regional cluster of the child is found and has the endpoints the child needs.
+func (w *ClusterDeploymentWebhook) validateChild(
+	ctx context.Context,
+	childClusterDeployment *kcmv1beta1.ClusterDeployment,
+	endpointTemplates *EndpointTemplates,
+) (field.ErrorList, error) {
+	regionalPath := field.NewPath("metadata", "labels").Key(KofRegionalClusterNameLabel)
+	if _, err := ReadClusterDeploymentConfig(getConfigRaw(childClusterDeployment)); err != nil {
+		return field.ErrorList{field.Invalid(field.NewPath("spec", "config"), "", err.Error())}, nil
+	}
+	for _, annotation := range []string{KofRegionalFailoverAnnotation, KofChildCredentialsAnnotation} {
+		if value, ok := childClusterDeployment.Annotations[annotation]; ok {
+			if _, err := strconv.ParseBool(value); err != nil {
+				return field.ErrorList{field.Invalid(
+					field.NewPath("metadata", "annotations").Key(annotation),
+					value,
+					"must be a boolean",
+				)}, nil
+			}
+		}
+	}
+
+	regionalClusterDeployment := &kcmv1beta1.ClusterDeployment{}
+	if regionalClusterName, ok := childClusterDeployment.Labels[KofRegionalClusterNameLabel]; ok {
+		err := w.Reader.Get(ctx, types.NamespacedName{
+			Name:      regionalClusterName,
+			Namespace: childClusterDeployment.Namespace,
+		}, regionalClusterDeployment)
+		if apierrors.IsNotFound(err) {
+			return field.ErrorList{field.Invalid(
+				regionalPath,
+				regionalClusterName,
+				"regional ClusterDeployment is not found in namespace "+childClusterDeployment.Namespace,
+			)}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if regionalClusterDeployment.Labels[KofClusterRoleLabel] != "regional" {
+			return field.ErrorList{field.Invalid(
+				regionalPath,
+				regionalClusterName,
+				fmt.Sprintf(`ClusterDeployment does not have label "%s: regional"`, KofClusterRoleLabel),
+			)}, nil
+		}
+	} else {
+		var err error
+		regionalClusterDeployment, err = findRegionalClusterDeploymentByLocation(ctx, w.Reader, childClusterDeployment)
+		if err != nil {
+			return nil, err
+		}
+		if regionalClusterDeployment == nil {
+			return field.ErrorList{field.Required(
+				regionalPath,
+				fmt.Sprintf(
+					"regional ClusterDeployment with matching location is not found, "+
+						"please set it explicitly or add it to ConfigMap %q",
+					KofRegionalClusterMappingConfigMapName,
+				),
+			)}, nil
+		}
+	}
+
+	tenantPath := field.NewPath("metadata", "labels").Key(KofTenantLabel)
+	tenant, err := findTenant(ctx, w.Reader, childClusterDeployment)
+	if errors.Is(err, errTenantNotFound) {
+		return field.ErrorList{field.Invalid(
+			tenantPath,
+			childClusterDeployment.Labels[KofTenantLabel],
+			err.Error(),
+		)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := getAdditionalWriteEndpoints(
+		ctx,
+		w.Reader,
+		childClusterDeployment,
+		regionalClusterDeployment.Name,
+		endpointTemplates,
+		tenant,
+	); err != nil {
+		var statusErr apierrors.APIStatus
+		if errors.As(err, &statusErr) && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		return field.ErrorList{field.Invalid(
+			field.NewPath("metadata", "annotations").Key(KofAdditionalRegionalClusterNamesAnnotation),
+			childClusterDeployment.Annotations[KofAdditionalRegionalClusterNamesAnnotation],
+			err.Error(),
+		)}, nil
+	}
+
+	if _, isIstio := regionalClusterDeployment.Labels[IstioRoleLabel]; isIstio {
+		if tenant != nil {
+			return field.ErrorList{field.Invalid(
+				tenantPath,
+				tenant.Name,
+				"tenants are not supported with istio regional cluster "+regionalClusterDeployment.Name,
+			)}, nil
+		}
+		if childCredentialsEnabled(childClusterDeployment) {
+			return field.ErrorList{field.Invalid(
+				field.NewPath("metadata", "annotations").Key(KofChildCredentialsAnnotation),
+				childClusterDeployment.Annotations[KofChildCredentialsAnnotation],
+				"child credentials are not supported with istio regional cluster "+regionalClusterDeployment.Name,
+			)}, nil
+		}
+		return nil, nil
+	}
+	if _, err := getChildClusterEndpoints(ctx, regionalClusterDeployment, endpointTemplates, tenant); err != nil {
+		return field.ErrorList{field.Invalid(
+			regionalPath,
+			regionalClusterDeployment.Name,
+			"cannot get endpoints of regional ClusterDeployment: "+err.Error(),
+		)}, nil
+	}
+	return nil, nil
+}
+
+// Checks the endpoints and the http client config of the regional cluster.
+func validateRegional(
+	ctx context.Context,
+	regionalClusterDeployment *kcmv1beta1.ClusterDeployment,
+	endpointTemplates *EndpointTemplates,
+) field.ErrorList {
+	configPath := field.NewPath("spec", "config")
+	var errs field.ErrorList
+
+	httpConfigPath := field.NewPath("metadata", "annotations").Key(KofRegionalHTTPClientConfigAnnotation)
+	if _, err := getRegionalHTTPClientConfig(regionalClusterDeployment); err != nil {
+		errs = append(errs, field.Invalid(
+			httpConfigPath,
+			regionalClusterDeployment.Annotations[KofRegionalHTTPClientConfigAnnotation],
+			err.Error(),
+		))
+	}
+
+	grafanaDatasourcesPath := field.NewPath("metadata", "annotations").Key(KofGrafanaDatasourcesAnnotation)
+	grafanaDatasources, err := getGrafanaDatasources(regionalClusterDeployment)
+	if err != nil {
+		errs = append(errs, field.Invalid(
+			grafanaDatasourcesPath,
+			regionalClusterDeployment.Annotations[KofGrafanaDatasourcesAnnotation],
+			err.Error(),
+		))
+	}
+
+	regionalClusterDeploymentConfig, err := ReadClusterDeploymentConfig(getConfigRaw(regionalClusterDeployment))
+	if err != nil {
+		return append(errs, field.Invalid(configPath, "", err.Error()))
+	}
+
+	endpointsPath := configPath.Child("clusterAnnotations")
+	if _, err := getEndpoint(
+		ctx,
+		ReadLogsAnnotation,
+		regionalClusterDeployment,
+		regionalClusterDeploymentConfig,
+		endpointTemplates,
+		nil,
+	); err != nil {
+		errs = append(errs, field.Required(endpointsPath.Key(KofRegionalDomainAnnotation), err.Error()))
+		return errs
+	}
+
+	metricsEndpoint, err := getEndpoint(
+		ctx,
+		ReadMetricsAnnotation,
+		regionalClusterDeployment,
+		regionalClusterDeploymentConfig,
+		endpointTemplates,
+		nil,
+	)
+	if err != nil {
+		errs = append(errs, field.Required(endpointsPath.Key(ReadMetricsAnnotation), err.Error()))
+		return errs
+	}
+	metricsURL, err := url.Parse(metricsEndpoint)
+	if err == nil {
+		_, err = getEndpointPort(metricsURL)
+	}
+	if err != nil {
+		errs = append(errs, field.Invalid(endpointsPath.Key(ReadMetricsAnnotation), metricsEndpoint, err.Error()))
+	}
+
+	if slices.Contains(grafanaDatasources, "traces") {
+		if _, err := getEndpoint(
+			ctx,
+			ReadTracesAnnotation,
+			regionalClusterDeployment,
+			regionalClusterDeploymentConfig,
+			endpointTemplates,
+			nil,
+		); err != nil {
+			errs = append(errs, field.Required(endpointsPath.Key(ReadTracesAnnotation), err.Error()))
+		}
+	}
+	return errs
+}
+
+// Returns true if labels, annotations or config read by `ReconcileKofClusterRole` are changed.
+func kofConfigChanged(oldClusterDeployment, clusterDeployment *kcmv1beta1.ClusterDeployment) bool {
+	for _, label := range []string{KofClusterRoleLabel, KofRegionalClusterNameLabel, IstioRoleLabel, KofTenantLabel} {
+		oldValue, oldOk := oldClusterDeployment.Labels[label]
+		value, ok := clusterDeployment.Labels[label]
+		if oldOk != ok || oldValue != value {
+			return true
+		}
+	}
+	for _, annotation := range []string{
+		KofRegionalHTTPClientConfigAnnotation,
+		KofRegionalFailoverAnnotation,
+		KofAdditionalRegionalClusterNamesAnnotation,
+		KofGrafanaDatasourcesAnnotation,
+		KofChildCredentialsAnnotation,
+	} {
+		oldValue, oldOk := oldClusterDeployment.Annotations[annotation]
+		value, ok := clusterDeployment.Annotations[annotation]
+		if oldOk != ok || oldValue != value {
+			return true
+		}
+	}
+	return oldClusterDeployment.Spec.Template != clusterDeployment.Spec.Template ||
+		clusterDeploymentConfigChanged(oldClusterDeployment, clusterDeployment)
+}
+
+// Returns true if the fields of `ClusterDeploymentConfig` are changed in the config,
+// or if the changed config cannot be read.
+func clusterDeploymentConfigChanged(oldClusterDeployment, clusterDeployment *kcmv1beta1.ClusterDeployment) bool {
+	oldConfigRaw, configRaw := getConfigRaw(oldClusterDeployment), getConfigRaw(clusterDeployment)
+	if bytes.Equal(oldConfigRaw, configRaw) {
+		return false
+	}
+	oldConfig, err := ReadClusterDeploymentConfig(oldConfigRaw)
+	if err != nil {
+		return true
+	}
+	config, err := ReadClusterDeploymentConfig(configRaw)
+	if err != nil {
+		return true
+	}
+	return !maps.Equal(oldConfig.ClusterAnnotations, config.ClusterAnnotations) ||
+		oldConfig.Region != config.Region ||
+		oldConfig.Location != config.Location ||
+		oldConfig.IdentityRef.Region != config.IdentityRef.Region ||
+		oldConfig.VSphere.Datacenter != config.VSphere.Datacenter
+}
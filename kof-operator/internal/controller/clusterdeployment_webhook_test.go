@@ -0,0 +1,285 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	kcmv1beta1 "github.com/K0rdent/kcm/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("ClusterDeployment Webhook", func() {
+	ctx := context.Background()
+
+	regionalConfig := fmt.Sprintf(`{
+		"region": "eu-west-1",
+		"clusterAnnotations": {"%s": "%s"}
+	}`, KofRegionalDomainAnnotation, "test-aws-ew1.kof.example.com")
+
+	newClusterDeployment := func(name string, labels map[string]string, config string) *kcmv1beta1.ClusterDeployment {
+		return &kcmv1beta1.ClusterDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   defaultNamespace,
+				Labels:      labels,
+				Annotations: map[string]string{},
+			},
+			Spec: kcmv1beta1.ClusterDeploymentSpec{
+				Template: "aws-cluster-template",
+				Config:   &apiextensionsv1.JSON{Raw: []byte(config)},
+			},
+		}
+	}
+
+	var created []client.Object
+
+	create := func(clusterDeployment *kcmv1beta1.ClusterDeployment) error {
+		err := k8sClient.Create(ctx, clusterDeployment)
+		if err == nil {
+			created = append(created, clusterDeployment)
+		}
+		return err
+	}
+
+	expectInvalid := func(err error, message string) {
+		Expect(errors.IsInvalid(err)).To(BeTrue(), "unexpected error: %v", err)
+		Expect(err).To(MatchError(ContainSubstring(message)))
+	}
+
+	AfterEach(func() {
+		for _, object := range created {
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, object))).To(Succeed())
+		}
+		created = nil
+	})
+
+	It("should ignore ClusterDeployment without kof role", func() {
+		clusterDeployment := newClusterDeployment("test-webhook-no-role", nil, `{"region": "eu-west-1"}`)
+		Expect(create(clusterDeployment)).To(Succeed())
+	})
+
+	It("should reject unknown kof role", func() {
+		clusterDeployment := newClusterDeployment("test-webhook-role", map[string]string{
+			KofClusterRoleLabel: "storage",
+		}, regionalConfig)
+		expectInvalid(create(clusterDeployment), `Unsupported value: "storage"`)
+	})
+
+	It("should accept valid regional and child ClusterDeployments", func() {
+		regional := newClusterDeployment("test-webhook-regional", map[string]string{
+			KofClusterRoleLabel: "regional",
+		}, regionalConfig)
+		Expect(create(regional)).To(Succeed())
+
+		child := newClusterDeployment("test-webhook-child", map[string]string{
+			KofClusterRoleLabel:         "child",
+			KofRegionalClusterNameLabel: regional.Name,
+		}, `{"region": "eu-west-1"}`)
+		Expect(create(child)).To(Succeed())
+
+		By("discovering regional cluster by location")
+		discoveredChild := newClusterDeployment("test-webhook-child-discovered", map[string]string{
+			KofClusterRoleLabel: "child",
+		}, `{"region": "eu-west-1"}`)
+		Expect(create(discoveredChild)).To(Succeed())
+	})
+
+	It("should reject invalid http config annotation of regional cluster", func() {
+		regional := newClusterDeployment("test-webhook-regional", map[string]string{
+			KofClusterRoleLabel: "regional",
+		}, regionalConfig)
+		regional.Annotations[KofRegionalHTTPClientConfigAnnotation] = `{"dial_timeout": "5s",}`
+		expectInvalid(create(regional), "metadata.annotations["+KofRegionalHTTPClientConfigAnnotation+"]")
+
+		By("rejecting several auth methods")
+		regional.Annotations[KofRegionalHTTPClientConfigAnnotation] = `{
+			"basic_auth": {"credentials_secret_name": "creds"},
+			"bearer_token": {"file": "/var/run/token"}
+		}`
+		expectInvalid(create(regional), "at most one of basic_auth, bearer_token and oauth2")
+
+		By("rejecting mTLS cert without key")
+		regional.Annotations[KofRegionalHTTPClientConfigAnnotation] = `{
+			"tls_config": {"cert": {"secret": {"name": "tls", "key": "tls.crt"}}}
+		}`
+		expectInvalid(create(regional), "both tls_config.cert and tls_config.key_secret")
+	})
+
+	It("should reject unsupported GrafanaDatasources of regional cluster", func() {
+		regional := newClusterDeployment("test-webhook-regional", map[string]string{
+			KofClusterRoleLabel: "regional",
+		}, regionalConfig)
+		regional.Annotations[KofGrafanaDatasourcesAnnotation] = "metrics,logs"
+		expectInvalid(create(regional), "metadata.annotations["+KofGrafanaDatasourcesAnnotation+"]")
+
+		regional.Annotations[KofGrafanaDatasourcesAnnotation] = "metrics,traces"
+		Expect(create(regional)).To(Succeed())
+	})
+
+	It("should reject regional cluster without endpoints", func() {
+		regional := newClusterDeployment("test-webhook-regional", map[string]string{
+			KofClusterRoleLabel: "regional",
+		}, `{"region": "eu-west-1"}`)
+		expectInvalid(create(regional), "neither endpoint nor regional domain is set")
+	})
+
+	It("should reject metrics endpoint without port", func() {
+		regional := newClusterDeployment("test-webhook-regional", map[string]string{
+			KofClusterRoleLabel: "regional",
+		}, fmt.Sprintf(`{
+			"region": "eu-west-1",
+			"clusterAnnotations": {"%s": "%s", "%s": "%s"}
+		}`,
+			KofRegionalDomainAnnotation, "test-aws-ew1.kof.example.com",
+			ReadMetricsAnnotation, "grpc://vmauth.test-aws-ew1.kof.example.com/vm/select/0/prometheus",
+		))
+		expectInvalid(create(regional), "cannot detect port of endpoint")
+	})
+
+	It("should reject invalid failover annotation of child cluster", func() {
+		regional := newClusterDeployment("test-webhook-regional", map[string]string{
+			KofClusterRoleLabel: "regional",
+		}, regionalConfig)
+		Expect(create(regional)).To(Succeed())
+
+		child := newClusterDeployment("test-webhook-child", map[string]string{
+			KofClusterRoleLabel: "child",
+		}, `{"region": "eu-west-1"}`)
+		child.Annotations[KofRegionalFailoverAnnotation] = "yes"
+		expectInvalid(create(child), "metadata.annotations["+KofRegionalFailoverAnnotation+"]")
+	})
+
+	It("should validate child credentials annotation of child cluster", func() {
+		regional := newClusterDeployment("test-webhook-regional", map[string]string{
+			KofClusterRoleLabel: "regional",
+		}, regionalConfig)
+		Expect(create(regional)).To(Succeed())
+
+		istioRegional := newClusterDeployment("test-webhook-regional-istio", map[string]string{
+			KofClusterRoleLabel: "regional",
+			IstioRoleLabel:      "child",
+		}, `{"region": "eu-west-1"}`)
+		Expect(create(istioRegional)).To(Succeed())
+
+		newChild := func(regionalClusterName string, childCredentials string) *kcmv1beta1.ClusterDeployment {
+			child := newClusterDeployment("test-webhook-child", map[string]string{
+				KofClusterRoleLabel:         "child",
+				KofRegionalClusterNameLabel: regionalClusterName,
+			}, `{"region": "eu-west-1"}`)
+			child.Annotations[KofChildCredentialsAnnotation] = childCredentials
+			return child
+		}
+
+		annotationPath := "metadata.annotations[" + KofChildCredentialsAnnotation + "]"
+		expectInvalid(create(newChild(regional.Name, "yes")), annotationPath)
+		expectInvalid(create(newChild(istioRegional.Name, "true")), "not supported with istio regional cluster")
+		Expect(create(newChild(regional.Name, "true"))).To(Succeed())
+	})
+
+	It("should validate additional regional clusters of child cluster", func() {
+		regional := newClusterDeployment("test-webhook-regional", map[string]string{
+			KofClusterRoleLabel: "regional",
+		}, regionalConfig)
+		Expect(create(regional)).To(Succeed())
+
+		drRegional := newClusterDeployment("test-webhook-regional-dr", map[string]string{
+			KofClusterRoleLabel: "regional",
+		}, regionalConfig)
+		Expect(create(drRegional)).To(Succeed())
+
+		newChild := func(additionalRegionalClusterNames string) *kcmv1beta1.ClusterDeployment {
+			child := newClusterDeployment("test-webhook-child", map[string]string{
+				KofClusterRoleLabel:         "child",
+				KofRegionalClusterNameLabel: regional.Name,
+			}, `{"region": "eu-west-1"}`)
+			child.Annotations[KofAdditionalRegionalClusterNamesAnnotation] = additionalRegionalClusterNames
+			return child
+		}
+
+		annotationPath := "metadata.annotations[" + KofAdditionalRegionalClusterNamesAnnotation + "]"
+		expectInvalid(create(newChild("missing-regional")), annotationPath)
+		expectInvalid(create(newChild(regional.Name)), "is already the regional cluster of the child")
+		Expect(create(newChild(drRegional.Name + ", "))).To(Succeed())
+	})
+
+	It("should reject child with unknown tenant", func() {
+		regional := newClusterDeployment("test-webhook-regional", map[string]string{
+			KofClusterRoleLabel: "regional",
+		}, regionalConfig)
+		Expect(create(regional)).To(Succeed())
+
+		child := newClusterDeployment("test-webhook-child", map[string]string{
+			KofClusterRoleLabel:         "child",
+			KofRegionalClusterNameLabel: regional.Name,
+			KofTenantLabel:              "team-a",
+		}, `{"region": "eu-west-1"}`)
+		expectInvalid(create(child), "metadata.labels["+KofTenantLabel+"]")
+	})
+
+	It("should reject child pointing at non-existent regional cluster", func() {
+		child := newClusterDeployment("test-webhook-child", map[string]string{
+			KofClusterRoleLabel:         "child",
+			KofRegionalClusterNameLabel: "missing-regional",
+		}, `{"region": "eu-west-1"}`)
+		expectInvalid(create(child), "regional ClusterDeployment is not found")
+	})
+
+	It("should reject child if regional cluster is not discovered by location", func() {
+		child := newClusterDeployment("test-webhook-child", map[string]string{
+			KofClusterRoleLabel: "child",
+		}, `{"region": "ap-south-1"}`)
+		expectInvalid(create(child), "regional ClusterDeployment with matching location is not found")
+	})
+
+	It("should validate only updates changing kof config", func() {
+		regional := newClusterDeployment("test-webhook-regional", map[string]string{
+			KofClusterRoleLabel: "regional",
+		}, regionalConfig)
+		Expect(create(regional)).To(Succeed())
+
+		child := newClusterDeployment("test-webhook-child", map[string]string{
+			KofClusterRoleLabel:         "child",
+			KofRegionalClusterNameLabel: regional.Name,
+		}, `{"region": "eu-west-1"}`)
+		Expect(create(child)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, regional)).To(Succeed())
+
+		By("updating unrelated label of child pointing at deleted regional cluster")
+		child.Labels["example.com/team"] = "observability"
+		Expect(k8sClient.Update(ctx, child)).To(Succeed())
+
+		By("updating unrelated config of child pointing at deleted regional cluster")
+		child.Spec.Config.Raw = []byte(`{"region": "eu-west-1", "controlPlaneNumber": 3}`)
+		Expect(k8sClient.Update(ctx, child)).To(Succeed())
+
+		By("updating region in config")
+		child.Spec.Config.Raw = []byte(`{"region": "eu-central-1", "controlPlaneNumber": 3}`)
+		expectInvalid(k8sClient.Update(ctx, child), "regional ClusterDeployment is not found")
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(child), child)).To(Succeed())
+
+		By("updating regional cluster name label")
+		child.Labels[KofRegionalClusterNameLabel] = "missing-regional"
+		expectInvalid(k8sClient.Update(ctx, child), "regional ClusterDeployment is not found")
+	})
+})
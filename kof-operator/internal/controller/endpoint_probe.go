@@ -0,0 +1,367 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	kcmv1beta1 "github.com/K0rdent/kcm/api/v1beta1"
+	kofv1beta1 "github.com/k0rdent/kof/kof-operator/api/v1beta1"
+	"github.com/k0rdent/kof/kof-operator/internal/controller/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// Served by vmauth and other VictoriaMetrics and VictoriaLogs components without auth.
+	vmauthHealthPath = "/health"
+	// Jaeger query UI behind the same ingress as the collector.
+	jaegerHealthPath = "/"
+)
+
+// EndpointProber probes the health of the endpoints written to child cluster ConfigMaps
+// from the management cluster every `Interval`, apart from the reconciliation of ClusterDeployments,
+// and reports the results by Events on change and by the EndpointsReachable condition of KofClusters.
+// The results are informational: they do not block the ConfigMaps,
+// as the management cluster may have no route to the endpoints.
+type EndpointProber struct {
+	Client     client.Client
+	HTTPClient *http.Client
+	Interval   time.Duration
+
+	// Last errors by endpoint name per child ClusterDeployment, to report the changes only.
+	lastErrors map[types.NamespacedName]map[string]string
+}
+
+func NewEndpointProber(c client.Client, interval time.Duration) *EndpointProber {
+	return &EndpointProber{
+		Client:     c,
+		HTTPClient: &http.Client{Timeout: 5 * time.Second},
+		Interval:   interval,
+		lastErrors: map[types.NamespacedName]map[string]string{},
+	}
+}
+
+// Start implements manager.Runnable, probing the child clusters every `Interval` until `ctx` is done.
+func (p *EndpointProber) Start(ctx context.Context) error {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		p.ProbeAll(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so only the leader updates KofClusters.
+func (p *EndpointProber) NeedLeaderElection() bool {
+	return true
+}
+
+// ProbeAll probes the endpoints of the child clusters with KofClusters once.
+func (p *EndpointProber) ProbeAll(ctx context.Context) {
+	log := log.FromContext(ctx)
+
+	kofClustersList := &kofv1beta1.KofClusterList{}
+	if err := p.Client.List(ctx, kofClustersList, client.MatchingLabels{
+		utils.ManagedByLabel: utils.ManagedByValue,
+	}); err != nil {
+		log.Error(err, "cannot list KofClusters to probe endpoints")
+		return
+	}
+
+	probed := map[types.NamespacedName]bool{}
+	for i := range kofClustersList.Items {
+		kofCluster := &kofClustersList.Items[i]
+		if kofCluster.Status.Role != "child" || !kofCluster.DeletionTimestamp.IsZero() {
+			continue
+		}
+		probed[client.ObjectKeyFromObject(kofCluster)] = true
+		if err := p.probeChildCluster(ctx, kofCluster); err != nil {
+			log.Error(err, "cannot probe endpoints of child cluster", "kofClusterName", kofCluster.Name)
+		}
+	}
+	for key := range p.lastErrors {
+		if !probed[key] {
+			delete(p.lastErrors, key)
+		}
+	}
+}
+
+// Probes the endpoints of the child cluster ConfigMap and reports them in the condition of `kofCluster`.
+func (p *EndpointProber) probeChildCluster(ctx context.Context, kofCluster *kofv1beta1.KofCluster) error {
+	key := client.ObjectKeyFromObject(kofCluster)
+	childClusterDeployment := &kcmv1beta1.ClusterDeployment{}
+	if err := p.Client.Get(ctx, key, childClusterDeployment); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := p.Client.Get(ctx, types.NamespacedName{
+		Name:      "kof-cluster-config-" + childClusterDeployment.Name,
+		Namespace: childClusterDeployment.Namespace,
+	}, configMap); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	additionalWriteEndpoints := map[string]AdditionalWriteEndpoints{}
+	if data, ok := configMap.Data[AdditionalWriteEndpointsKey]; ok {
+		if err := yaml.Unmarshal([]byte(data), &additionalWriteEndpoints); err != nil {
+			return err
+		}
+	}
+
+	original := kofCluster.DeepCopy()
+	endpoints := getProbedEndpoints(configMap.Data, additionalWriteEndpoints)
+	if len(endpoints) == 0 {
+		delete(p.lastErrors, key)
+		meta.RemoveStatusCondition(&kofCluster.Status.Conditions, kofv1beta1.KofClusterEndpointsReachableCondition)
+		return p.patchKofClusterStatus(ctx, original, kofCluster)
+	}
+
+	username, password, err := p.getCredentials(ctx, childClusterDeployment.Namespace, configMap.Data)
+	if err != nil {
+		utils.LogEvent(
+			ctx,
+			"EndpointProbeFailed",
+			"Failed to read credentials to probe endpoints of the child cluster",
+			childClusterDeployment,
+			err,
+		)
+		setKofClusterCondition(
+			kofCluster,
+			kofv1beta1.KofClusterEndpointsReachableCondition,
+			metav1.ConditionUnknown,
+			"EndpointProbeFailed",
+			err.Error(),
+		)
+		return p.patchKofClusterStatus(ctx, original, kofCluster)
+	}
+
+	endpointErrors := p.probeEndpoints(ctx, endpoints, username, password)
+	lastEndpointErrors, probedBefore := p.lastErrors[key]
+	p.lastErrors[key] = endpointErrors
+	for _, name := range slices.Sorted(maps.Keys(endpoints)) {
+		errMessage, failed := endpointErrors[name]
+		lastErrMessage, lastFailed := lastEndpointErrors[name]
+		if failed && (!probedBefore || errMessage != lastErrMessage) {
+			utils.LogEvent(
+				ctx,
+				"EndpointUnreachable",
+				"Endpoint of the child cluster is unreachable",
+				childClusterDeployment,
+				fmt.Errorf("%s", errMessage),
+				"endpointName", name,
+				"endpoint", endpoints[name],
+			)
+		} else if !failed && lastFailed {
+			utils.LogEvent(
+				ctx,
+				"EndpointReachable",
+				"Endpoint of the child cluster is reachable again",
+				childClusterDeployment,
+				nil,
+				"endpointName", name,
+				"endpoint", endpoints[name],
+			)
+		}
+	}
+
+	setEndpointsReachableKofClusterCondition(kofCluster, endpoints, endpointErrors)
+	return p.patchKofClusterStatus(ctx, original, kofCluster)
+}
+
+// Patches the status of `kofCluster` if it changed, failing on a concurrent update,
+// which is left for the next probe.
+func (p *EndpointProber) patchKofClusterStatus(
+	ctx context.Context,
+	original *kofv1beta1.KofCluster,
+	kofCluster *kofv1beta1.KofCluster,
+) error {
+	if equality.Semantic.DeepEqual(original.Status, kofCluster.Status) {
+		return nil
+	}
+	return p.Client.Status().Patch(
+		ctx,
+		kofCluster,
+		client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{}),
+	)
+}
+
+// Probes `endpoints` by name concurrently with basic auth if `username` is not empty.
+// Returns the errors by endpoint name, the healthy endpoints are not included.
+func (p *EndpointProber) probeEndpoints(
+	ctx context.Context,
+	endpoints map[string]string,
+	username, password string,
+) map[string]string {
+	endpointErrors := map[string]string{}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for name, endpoint := range endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.probe(ctx, name, endpoint, username, password); err != nil {
+				mutex.Lock()
+				defer mutex.Unlock()
+				endpointErrors[name] = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+	return endpointErrors
+}
+
+func (p *EndpointProber) probe(ctx context.Context, name, endpoint, username, password string) error {
+	healthURL, err := getHealthURL(name, endpoint)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL, nil)
+	if err != nil {
+		return err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	res, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 256))
+		return fmt.Errorf("GET %s: unexpected status %s: %s", healthURL, res.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// Returns the health URL on the host of the endpoint: Jaeger for traces, vmauth for others.
+func getHealthURL(name, endpoint string) (string, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if endpointURL.Scheme == "" || endpointURL.Host == "" {
+		return "", fmt.Errorf("invalid endpoint %q: scheme and host are required", endpoint)
+	}
+	healthPath := vmauthHealthPath
+	if name == WriteTracesKey || strings.HasSuffix(name, "/traces") {
+		healthPath = jaegerHealthPath
+	}
+	healthURL := url.URL{Scheme: endpointURL.Scheme, Host: endpointURL.Host, Path: healthPath}
+	return healthURL.String(), nil
+}
+
+// Returns the endpoints of the child cluster ConfigMap data to probe by name.
+func getProbedEndpoints(
+	configData map[string]string,
+	additionalWriteEndpoints map[string]AdditionalWriteEndpoints,
+) map[string]string {
+	endpoints := map[string]string{}
+	for _, key := range []string{ReadMetricsKey, WriteMetricsKey, WriteLogsKey, WriteTracesKey} {
+		if endpoint := configData[key]; endpoint != "" {
+			endpoints[key] = endpoint
+		}
+	}
+	for regionalClusterName, additional := range additionalWriteEndpoints {
+		for name, endpoint := range map[string]string{
+			"metrics": additional.Metrics.Endpoint,
+			"logs":    additional.Logs.Endpoint,
+			"traces":  additional.Traces.Endpoint,
+		} {
+			if endpoint != "" {
+				endpoints[regionalClusterName+"/"+name] = endpoint
+			}
+		}
+	}
+	return endpoints
+}
+
+// Returns the credentials the child cluster uses to write to its regional clusters:
+// its own from `CredentialsSecretNameKey` or the shared `KofStorageSecretName` in the release namespace.
+func (p *EndpointProber) getCredentials(
+	ctx context.Context,
+	childNamespace string,
+	configData map[string]string,
+) (string, string, error) {
+	secretKey := types.NamespacedName{Name: KofStorageSecretName}
+	if secretName, ok := configData[CredentialsSecretNameKey]; ok {
+		secretKey = types.NamespacedName{Name: secretName, Namespace: childNamespace}
+	} else {
+		releaseNamespace, ok := os.LookupEnv("RELEASE_NAMESPACE")
+		if !ok {
+			return "", "", fmt.Errorf("required RELEASE_NAMESPACE env var is not set")
+		}
+		secretKey.Namespace = releaseNamespace
+	}
+
+	secret := &corev1.Secret{}
+	if err := p.Client.Get(ctx, secretKey, secret); err != nil {
+		return "", "", fmt.Errorf("cannot read Secret %s: %w", secretKey, err)
+	}
+	return string(secret.Data["username"]), string(secret.Data["password"]), nil
+}
+
+// Reports the probe of `endpoints` with `endpointErrors` in the EndpointsReachable condition.
+func setEndpointsReachableKofClusterCondition(
+	kofCluster *kofv1beta1.KofCluster,
+	endpoints map[string]string,
+	endpointErrors map[string]string,
+) {
+	if len(endpointErrors) == 0 {
+		setKofClusterCondition(
+			kofCluster,
+			kofv1beta1.KofClusterEndpointsReachableCondition,
+			metav1.ConditionTrue,
+			"EndpointsReachable",
+			fmt.Sprintf("All %d endpoints are reachable", len(endpoints)),
+		)
+		return
+	}
+	messages := make([]string, 0, len(endpointErrors))
+	for _, name := range slices.Sorted(maps.Keys(endpointErrors)) {
+		messages = append(messages, name+": "+endpointErrors[name])
+	}
+	setKofClusterCondition(
+		kofCluster,
+		kofv1beta1.KofClusterEndpointsReachableCondition,
+		metav1.ConditionFalse,
+		"EndpointsUnreachable",
+		strings.Join(messages, "; "),
+	)
+}
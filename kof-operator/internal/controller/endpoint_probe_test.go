@@ -0,0 +1,43 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Endpoint probe", func() {
+	It("should probe only the set endpoints of child cluster", func() {
+		Expect(getProbedEndpoints(
+			map[string]string{
+				ReadMetricsKey:  "https://vmauth.eu.example.com/vm/select/0/prometheus",
+				WriteMetricsKey: "https://vmauth.eu.example.com/vm/insert/0/prometheus/api/v1/write",
+				WriteLogsKey:    "",
+			},
+			map[string]AdditionalWriteEndpoints{
+				"regional-us": {
+					Metrics: CollectorsEndpoint{Endpoint: "https://vmauth.us.example.com/vm/insert/0/prometheus/api/v1/write"},
+				},
+			},
+		)).To(Equal(map[string]string{
+			ReadMetricsKey:        "https://vmauth.eu.example.com/vm/select/0/prometheus",
+			WriteMetricsKey:       "https://vmauth.eu.example.com/vm/insert/0/prometheus/api/v1/write",
+			"regional-us/metrics": "https://vmauth.us.example.com/vm/insert/0/prometheus/api/v1/write",
+		}))
+	})
+})
@@ -0,0 +1,258 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+
+	kcmv1beta1 "github.com/K0rdent/kcm/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+// Optional ConfigMap in the release namespace, overriding the templates of regional cluster endpoints.
+const KofEndpointTemplatesConfigMapName = "kof-endpoint-templates"
+const EndpointTemplatesKey = "templates"
+
+// Names of the endpoints in `EndpointTemplates`, as in `KofClusterEndpoints`:
+var endpointNames = map[string]string{
+	WriteMetricsAnnotation: "writeMetrics",
+	ReadMetricsAnnotation:  "readMetrics",
+	WriteLogsAnnotation:    "writeLogs",
+	ReadLogsAnnotation:     "readLogs",
+	WriteTracesAnnotation:  "writeTraces",
+	ReadTracesAnnotation:   "readTraces",
+}
+
+// EndpointTemplates override `defaultEndpoints` and `istioEndpoints` by endpoint name, e.g. `writeMetrics`.
+// They are Go templates with the variables of `getEndpointTemplateData`, e.g.
+// `https://vmauth-{{ .ClusterName }}.{{ .Domain }}/vm/insert/{{ .TenantID }}/prometheus/api/v1/write`.
+// Endpoint annotations of the regional cluster take precedence over the templates.
+type EndpointTemplates struct {
+	// Default templates of the endpoints of regional clusters.
+	Default map[string]string `json:"default,omitempty"`
+
+	// Istio templates of the endpoints of regional clusters with `IstioRoleLabel`,
+	// falling back to `Default` for other endpoints.
+	Istio map[string]string `json:"istio,omitempty"`
+
+	// Storage locates kof-storage in regional clusters for the VMUsers of child credentials.
+	Storage StorageServices `json:"storage,omitempty"`
+}
+
+// Namespace of kof-storage in regional clusters and of kof-collectors in child clusters by default.
+const defaultStorageNamespace = "kof"
+
+// StorageServices are the kof-storage services targeted by the VMUsers of child credentials.
+type StorageServices struct {
+	// Namespace of kof-storage in regional clusters and of kof-collectors in child clusters,
+	// where the VMUser and the credentials Secret are deployed, `defaultStorageNamespace` by default.
+	Namespace string `json:"namespace,omitempty"`
+
+	// VMInsertURL of VictoriaMetrics insert service, `vminsert-cluster` in the `Namespace` by default.
+	VMInsertURL string `json:"vminsertURL,omitempty"`
+
+	// VMSelectURL of VictoriaMetrics select service, `vmselect-cluster` in the `Namespace` by default.
+	VMSelectURL string `json:"vmselectURL,omitempty"`
+
+	// VLInsertURL of VictoriaLogs insert service, the one of kof-storage release in the `Namespace` by default.
+	VLInsertURL string `json:"vlinsertURL,omitempty"`
+}
+
+// Returns the templates from the `KofEndpointTemplatesConfigMapName` ConfigMap,
+// or empty templates if it is not found, so only the built-in ones are used.
+func readEndpointTemplates(ctx context.Context, reader client.Reader) (*EndpointTemplates, error) {
+	log := log.FromContext(ctx)
+
+	releaseNamespace, ok := os.LookupEnv("RELEASE_NAMESPACE")
+	if !ok {
+		return nil, fmt.Errorf("required RELEASE_NAMESPACE env var is not set")
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := reader.Get(ctx, types.NamespacedName{
+		Name:      KofEndpointTemplatesConfigMapName,
+		Namespace: releaseNamespace,
+	}, configMap); err != nil {
+		if errors.IsNotFound(err) {
+			return &EndpointTemplates{}, nil
+		}
+		log.Error(
+			err, "cannot read endpoint templates ConfigMap",
+			"configMapName", KofEndpointTemplatesConfigMapName,
+		)
+		return nil, err
+	}
+
+	templates, err := parseEndpointTemplates(configMap.Data[EndpointTemplatesKey])
+	if err != nil {
+		log.Error(
+			err, "invalid endpoint templates",
+			"configMapName", KofEndpointTemplatesConfigMapName,
+			"key", EndpointTemplatesKey,
+		)
+		return nil, err
+	}
+	return templates, nil
+}
+
+func parseEndpointTemplates(data string) (*EndpointTemplates, error) {
+	templates := &EndpointTemplates{}
+	if err := yaml.UnmarshalStrict([]byte(data), templates); err != nil {
+		return nil, err
+	}
+
+	names := slices.Sorted(maps.Values(endpointNames))
+	for group, groupTemplates := range map[string]map[string]string{
+		"default": templates.Default,
+		"istio":   templates.Istio,
+	} {
+		for name, text := range groupTemplates {
+			if !slices.Contains(names, name) {
+				return nil, fmt.Errorf(
+					"%s: unsupported endpoint %q, supported: %s", group, name, strings.Join(names, ", "),
+				)
+			}
+			if _, err := parseEndpointTemplate(text); err != nil {
+				return nil, fmt.Errorf("%s: invalid template of endpoint %q: %w", group, name, err)
+			}
+			// Otherwise children of all tenants would use the default tenant of metrics.
+			if isMetricsEndpointName(name) && !strings.Contains(text, ".TenantID") {
+				return nil, fmt.Errorf("%s: template of endpoint %q does not use {{ .TenantID }}", group, name)
+			}
+		}
+	}
+
+	if namespace := templates.Storage.Namespace; namespace != "" {
+		if msgs := validation.IsDNS1123Label(namespace); len(msgs) > 0 {
+			return nil, fmt.Errorf("storage: invalid namespace %q: %s", namespace, msgs[0])
+		}
+	}
+	for name, serviceURL := range map[string]string{
+		"vminsertURL": templates.Storage.VMInsertURL,
+		"vmselectURL": templates.Storage.VMSelectURL,
+		"vlinsertURL": templates.Storage.VLInsertURL,
+	} {
+		if serviceURL == "" {
+			continue
+		}
+		if parsedURL, err := url.Parse(serviceURL); err != nil || parsedURL.Scheme == "" || parsedURL.Host == "" {
+			return nil, fmt.Errorf("storage: invalid %s %q: scheme and host are required", name, serviceURL)
+		}
+	}
+	return templates, nil
+}
+
+func isMetricsEndpointName(name string) bool {
+	return name == endpointNames[ReadMetricsAnnotation] || name == endpointNames[WriteMetricsAnnotation]
+}
+
+func parseEndpointTemplate(text string) (*template.Template, error) {
+	return template.New("endpoint").Option("missingkey=error").Parse(text)
+}
+
+// Returns the template of the endpoint of the `endpointAnnotation`,
+// from the overrides of `t` if set, or from the built-in ones if `t` is nil or has no override.
+func (t *EndpointTemplates) get(endpointAnnotation string, isIstio bool) string {
+	if t == nil {
+		t = &EndpointTemplates{}
+	}
+	name := endpointNames[endpointAnnotation]
+	if isIstio {
+		if text, ok := t.Istio[name]; ok {
+			return text
+		}
+		if text, ok := istioEndpoints[endpointAnnotation]; ok {
+			return text
+		}
+	}
+	if text, ok := t.Default[name]; ok {
+		return text
+	}
+	return defaultEndpoints[endpointAnnotation]
+}
+
+// Returns the kof-storage services of `t` with the defaults for the unset ones.
+func (t *EndpointTemplates) getStorageServices() StorageServices {
+	if t == nil {
+		t = &EndpointTemplates{}
+	}
+	storage := t.Storage
+	if storage.Namespace == "" {
+		storage.Namespace = defaultStorageNamespace
+	}
+	if storage.VMInsertURL == "" {
+		storage.VMInsertURL = "http://vminsert-cluster." + storage.Namespace + ".svc:8480"
+	}
+	if storage.VMSelectURL == "" {
+		storage.VMSelectURL = "http://vmselect-cluster." + storage.Namespace + ".svc:8481"
+	}
+	if storage.VLInsertURL == "" {
+		storage.VLInsertURL = "http://kof-storage-victoria-logs-cluster-vlinsert." + storage.Namespace + ".svc:9481"
+	}
+	return storage
+}
+
+// Returns the variables of endpoint templates of the regional cluster:
+// `Domain` from `KofRegionalDomainAnnotation` if it is set,
+// `ClusterName` and `Namespace` of the regional ClusterDeployment,
+// `Tenant` name, empty for the default nil `tenant`,
+// and `TenantID` in the `accountID[:projectID]` form, "0" for the default tenant.
+func getEndpointTemplateData(
+	regionalClusterDeployment *kcmv1beta1.ClusterDeployment,
+	regionalClusterDeploymentConfig *ClusterDeploymentConfig,
+	tenant *Tenant,
+) map[string]string {
+	data := map[string]string{
+		"ClusterName": regionalClusterDeployment.Name,
+		"Namespace":   regionalClusterDeployment.Namespace,
+		"Tenant":      "",
+		"TenantID":    "0",
+	}
+	if domain, ok := regionalClusterDeploymentConfig.ClusterAnnotations[KofRegionalDomainAnnotation]; ok {
+		data["Domain"] = domain
+	}
+	if tenant != nil {
+		data["Tenant"] = tenant.Name
+		data["TenantID"] = tenant.metricsTenantID()
+	}
+	return data
+}
+
+func renderEndpointTemplate(text string, data map[string]string) (string, error) {
+	endpointTemplate, err := parseEndpointTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	var endpoint strings.Builder
+	if err := endpointTemplate.Execute(&endpoint, data); err != nil {
+		return "", err
+	}
+	return endpoint.String(), nil
+}
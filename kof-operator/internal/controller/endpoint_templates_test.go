@@ -0,0 +1,156 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	kcmv1beta1 "github.com/K0rdent/kcm/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Endpoint templates", func() {
+	const templates = `
+default:
+  writeMetrics: "https://metrics-{{ .ClusterName }}.{{ .Domain }}/vm/insert/{{ .TenantID }}/prometheus/api/v1/write"
+  writeLogs: "https://logs-{{ .Tenant }}.{{ .Domain }}/insert/opentelemetry/v1/logs"
+istio:
+  readMetrics: "http://{{ .ClusterName }}-vmselect.{{ .Namespace }}:8481/select/{{ .TenantID }}/prometheus"
+`
+
+	DescribeTable("should resolve endpoints of regional cluster",
+		func(
+			labels map[string]string,
+			clusterAnnotations map[string]string,
+			endpointAnnotation string,
+			tenant *Tenant,
+			expected string,
+		) {
+			endpointTemplates, err := parseEndpointTemplates(templates)
+			Expect(err).NotTo(HaveOccurred())
+
+			regionalClusterDeployment := &kcmv1beta1.ClusterDeployment{ObjectMeta: metav1.ObjectMeta{
+				Name:      "regional",
+				Namespace: "kcm-system",
+				Labels:    labels,
+			}}
+			regionalClusterDeploymentConfig := &ClusterDeploymentConfig{ClusterAnnotations: clusterAnnotations}
+			Expect(getEndpoint(
+				ctx,
+				endpointAnnotation,
+				regionalClusterDeployment,
+				regionalClusterDeploymentConfig,
+				endpointTemplates,
+				tenant,
+			)).To(Equal(expected))
+		},
+		Entry("built-in template",
+			nil, map[string]string{KofRegionalDomainAnnotation: "kof.example.com"},
+			ReadLogsAnnotation, nil,
+			"https://vmauth.kof.example.com/vls"),
+		Entry("overridden template",
+			nil, map[string]string{KofRegionalDomainAnnotation: "kof.example.com"},
+			WriteMetricsAnnotation, nil,
+			"https://metrics-regional.kof.example.com/vm/insert/0/prometheus/api/v1/write"),
+		Entry("overridden template of tenant",
+			nil, map[string]string{KofRegionalDomainAnnotation: "kof.example.com"},
+			WriteLogsAnnotation, &Tenant{Name: "team-a", AccountID: 42},
+			"https://logs-team-a.kof.example.com/insert/opentelemetry/v1/logs"),
+		Entry("built-in template of tenant",
+			nil, map[string]string{KofRegionalDomainAnnotation: "kof.example.com"},
+			ReadMetricsAnnotation, &Tenant{Name: "team-a", AccountID: 42, ProjectID: 7},
+			"https://vmauth.kof.example.com/vm/select/42:7/prometheus"),
+		Entry("annotation of tenant",
+			nil, map[string]string{
+				KofRegionalDomainAnnotation: "kof.example.com",
+				WriteMetricsAnnotation:      "https://vmauth.custom.example.com/vm/insert/0/prometheus/api/v1/write",
+			},
+			WriteMetricsAnnotation, &Tenant{Name: "team-a", AccountID: 42},
+			"https://vmauth.custom.example.com/vm/insert/42/prometheus/api/v1/write"),
+		Entry("annotation taking precedence",
+			nil, map[string]string{
+				KofRegionalDomainAnnotation: "kof.example.com",
+				WriteMetricsAnnotation:      "https://vmauth.custom.example.com/vm/insert/0/prometheus/api/v1/write",
+			},
+			WriteMetricsAnnotation, nil,
+			"https://vmauth.custom.example.com/vm/insert/0/prometheus/api/v1/write"),
+		Entry("overridden istio template",
+			map[string]string{IstioRoleLabel: "child"}, nil,
+			ReadMetricsAnnotation, nil,
+			"http://regional-vmselect.kcm-system:8481/select/0/prometheus"),
+		Entry("overridden istio template of tenant",
+			map[string]string{IstioRoleLabel: "child"}, nil,
+			ReadMetricsAnnotation, &Tenant{Name: "team-a", AccountID: 42},
+			"http://regional-vmselect.kcm-system:8481/select/42/prometheus"),
+		Entry("built-in istio template",
+			map[string]string{IstioRoleLabel: "child"}, nil,
+			ReadLogsAnnotation, nil,
+			"http://regional-logs-select:9471"),
+	)
+
+	It("should require regional domain in templates using it", func() {
+		_, err := getEndpoint(
+			ctx,
+			WriteTracesAnnotation,
+			&kcmv1beta1.ClusterDeployment{ObjectMeta: metav1.ObjectMeta{Name: "regional"}},
+			&ClusterDeploymentConfig{},
+			nil,
+			nil,
+		)
+		Expect(err).To(MatchError(ContainSubstring("neither endpoint nor regional domain is set")))
+	})
+
+	DescribeTable("should reject invalid endpoint templates",
+		func(data string, message string) {
+			_, err := parseEndpointTemplates(data)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("unsupported endpoint", `{"default": {"writeEvents": "https://{{ .Domain }}"}}`,
+			"unsupported endpoint"),
+		Entry("invalid template", `{"istio": {"readLogs": "http://{{ .ClusterName"}}`,
+			"invalid template"),
+		Entry("metrics template without tenant",
+			`{"istio": {"readMetrics": "http://{{ .ClusterName }}-vmselect:8481/select/0/prometheus"}}`,
+			`template of endpoint "readMetrics" does not use {{ .TenantID }}`),
+		Entry("unknown field", `{"defaults": {"readLogs": "https://{{ .Domain }}"}}`,
+			"unknown field"),
+		Entry("invalid storage namespace", `{"storage": {"namespace": "Kof"}}`,
+			"invalid namespace"),
+		Entry("invalid storage URL", `{"storage": {"vminsertURL": "vminsert:8480"}}`,
+			"invalid vminsertURL"),
+	)
+
+	It("should return storage services with defaults in the storage namespace", func() {
+		endpointTemplates, err := parseEndpointTemplates(`
+storage:
+  namespace: monitoring
+  vlinsertURL: "http://logs-vlinsert.monitoring.svc:9481"
+`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(endpointTemplates.getStorageServices()).To(Equal(StorageServices{
+			Namespace:   "monitoring",
+			VMInsertURL: "http://vminsert-cluster.monitoring.svc:8480",
+			VMSelectURL: "http://vmselect-cluster.monitoring.svc:8481",
+			VLInsertURL: "http://logs-vlinsert.monitoring.svc:9481",
+		}))
+
+		var noTemplates *EndpointTemplates
+		Expect(noTemplates.getStorageServices().VLInsertURL).To(Equal(
+			"http://kof-storage-victoria-logs-cluster-vlinsert.kof.svc:9481",
+		))
+	})
+})
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strconv"
+
+	grafanav1beta1 "github.com/grafana/grafana-operator/v5/api/v1beta1"
+	kofv1beta1 "github.com/k0rdent/kof/kof-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// GrafanaDatasourceSettings collects `jsonData`, `secureJsonData` and `valuesFrom`
+// of a GrafanaDatasource, so auth and TLS settings can be combined.
+type GrafanaDatasourceSettings struct {
+	JSONData       map[string]any
+	SecureJSONData map[string]string
+	ValuesFrom     []grafanav1beta1.ValueFrom
+}
+
+func NewGrafanaDatasourceSettings() *GrafanaDatasourceSettings {
+	return &GrafanaDatasourceSettings{
+		JSONData:       map[string]any{},
+		SecureJSONData: map[string]string{},
+	}
+}
+
+// SetHTTPClientConfig maps the `kof-http-config` of the regional cluster to the datasource settings.
+func (s *GrafanaDatasourceSettings) SetHTTPClientConfig(httpClientConfig *kofv1beta1.HTTPClientConfig) {
+	tlsConfig := &httpClientConfig.TLSConfig
+	s.JSONData["tlsSkipVerify"] = tlsConfig.InsecureSkipVerify
+	s.JSONData["timeout"] = strconv.Itoa(int(httpClientConfig.DialTimeout.Duration.Seconds()))
+	if tlsConfig.ServerName != "" {
+		s.JSONData["serverName"] = tlsConfig.ServerName
+	}
+	if tlsConfig.CA != nil {
+		s.JSONData["tlsAuthWithCACert"] = true
+		s.setSecureValueFrom("tlsCACert", tlsConfig.CA.Secret, tlsConfig.CA.ConfigMap)
+	}
+	if tlsConfig.Cert != nil && tlsConfig.KeySecret != nil {
+		s.JSONData["tlsAuth"] = true
+		s.setSecureValueFrom("tlsClientCert", tlsConfig.Cert.Secret, tlsConfig.Cert.ConfigMap)
+		s.setSecureValueFrom("tlsClientKey", tlsConfig.KeySecret, nil)
+	}
+}
+
+// SetAuth maps bearer token auth of `httpClientConfig` to the datasource settings.
+// Returns false if the configured auth can't be used by Grafana:
+// OAuth2 client credentials and token files in the promxy pod.
+func (s *GrafanaDatasourceSettings) SetAuth(httpClientConfig *kofv1beta1.HTTPClientConfig) bool {
+	if httpClientConfig.OAuth2 != nil {
+		return false
+	}
+	bearerToken := httpClientConfig.BearerToken
+	if bearerToken == nil {
+		return true
+	}
+	if bearerToken.Secret == nil {
+		return false
+	}
+	s.JSONData["httpHeaderName1"] = "Authorization"
+	s.setSecureValueFrom("httpHeaderValue1", bearerToken.Secret, nil)
+	s.SecureJSONData["httpHeaderValue1"] = "Bearer " + s.SecureJSONData["httpHeaderValue1"]
+	return true
+}
+
+// SetHeaders adds `headers` sorted by name after the headers set before, e.g. by `SetAuth`.
+func (s *GrafanaDatasourceSettings) SetHeaders(headers map[string]string) {
+	index := 1
+	for _, name := range slices.Sorted(maps.Keys(headers)) {
+		for s.JSONData["httpHeaderName"+strconv.Itoa(index)] != nil {
+			index++
+		}
+		s.JSONData["httpHeaderName"+strconv.Itoa(index)] = name
+		s.SecureJSONData["httpHeaderValue"+strconv.Itoa(index)] = headers[name]
+	}
+}
+
+// SetBasicAuth reads basic auth credentials from the `username` and `password` keys of `secretName`.
+func (s *GrafanaDatasourceSettings) SetBasicAuth(datasource *grafanav1beta1.GrafanaDatasource, secretName string) {
+	datasource.Spec.Datasource.BasicAuthUser = "${username}" // Set in `ValuesFrom`.
+	s.ValuesFrom = append(s.ValuesFrom, grafanav1beta1.ValueFrom{
+		TargetPath: "basicAuthUser",
+		ValueFrom: grafanav1beta1.ValueFromSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  "username",
+			},
+		},
+	})
+	s.setSecureValueFrom("basicAuthPassword", &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+		Key:                  "password",
+	}, nil)
+}
+
+// Sets `secureJsonData.<field>` to the value of the selected key.
+// The grafana-operator replaces `${<key>}` with the value of the key.
+func (s *GrafanaDatasourceSettings) setSecureValueFrom(
+	field string,
+	secret *corev1.SecretKeySelector,
+	configMap *corev1.ConfigMapKeySelector,
+) {
+	valueFrom := grafanav1beta1.ValueFrom{TargetPath: "secureJsonData." + field}
+	if secret != nil {
+		valueFrom.ValueFrom.SecretKeyRef = secret
+		s.SecureJSONData[field] = "${" + secret.Key + "}"
+	} else if configMap != nil {
+		valueFrom.ValueFrom.ConfigMapKeyRef = configMap
+		s.SecureJSONData[field] = "${" + configMap.Key + "}"
+	} else {
+		return
+	}
+	s.ValuesFrom = append(s.ValuesFrom, valueFrom)
+}
+
+// Apply writes the collected settings to `datasource`.
+func (s *GrafanaDatasourceSettings) Apply(datasource *grafanav1beta1.GrafanaDatasource) error {
+	if len(s.JSONData) > 0 {
+		jsonData, err := json.Marshal(s.JSONData)
+		if err != nil {
+			return err
+		}
+		datasource.Spec.Datasource.JSONData = jsonData
+	}
+	if len(s.SecureJSONData) > 0 {
+		secureJSONData, err := json.Marshal(s.SecureJSONData)
+		if err != nil {
+			return err
+		}
+		datasource.Spec.Datasource.SecureJSONData = secureJSONData
+	}
+	datasource.Spec.ValuesFrom = s.ValuesFrom
+	return nil
+}
@@ -0,0 +1,319 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	kcmv1beta1 "github.com/K0rdent/kcm/api/v1beta1"
+	kofv1beta1 "github.com/k0rdent/kof/kof-operator/api/v1beta1"
+	istio "github.com/k0rdent/kof/kof-operator/internal/controller/istio"
+	remotesecret "github.com/k0rdent/kof/kof-operator/internal/controller/istio/remote-secret"
+	"github.com/k0rdent/kof/kof-operator/internal/controller/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+// +kubebuilder:rbac:groups=kof.k0rdent.mirantis.com,resources=kofclusters,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=kof.k0rdent.mirantis.com,resources=kofclusters/status,verbs=get;update;patch
+
+// Creates or updates the KofCluster summarising the kof and Istio setup of the ClusterDeployment,
+// or deletes it if the ClusterDeployment has neither kof nor Istio role.
+// `reconcileErr` is reported in the Reconciled condition.
+func (r *ClusterDeploymentReconciler) reconcileKofCluster(
+	ctx context.Context,
+	clusterDeployment *kcmv1beta1.ClusterDeployment,
+	reconcileErr error,
+) error {
+	log := log.FromContext(ctx)
+
+	if !clusterDeployment.DeletionTimestamp.IsZero() {
+		// KofCluster is owned by the ClusterDeployment and deleted with it.
+		return nil
+	}
+
+	kofCluster := &kofv1beta1.KofCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterDeployment.Name,
+			Namespace: clusterDeployment.Namespace,
+		},
+	}
+
+	_, hasRole := clusterDeployment.Labels[KofClusterRoleLabel]
+	_, isIstio := clusterDeployment.Labels[IstioRoleLabel]
+	if !hasRole && !isIstio {
+		return r.deleteIfManaged(ctx, kofCluster, "KofCluster")
+	}
+
+	ownerReference, err := utils.GetOwnerReference(clusterDeployment, r.Client)
+	if err != nil {
+		log.Error(err, "cannot get owner reference from ClusterDeployment", "name", clusterDeployment.Name)
+		return err
+	}
+	kofCluster.Labels = map[string]string{utils.ManagedByLabel: utils.ManagedByValue}
+	kofCluster.OwnerReferences = []metav1.OwnerReference{ownerReference}
+
+	if _, err := r.createOrUpdate(ctx, kofCluster, "KofCluster", []any{
+		"kofClusterName", kofCluster.Name,
+	}); err != nil {
+		return err
+	}
+	if kofCluster.Labels[utils.ManagedByLabel] != utils.ManagedByValue {
+		return nil
+	}
+
+	original := kofCluster.DeepCopy()
+	if err := r.setKofClusterStatus(ctx, kofCluster, clusterDeployment, reconcileErr); err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(original.Status, kofCluster.Status) {
+		return nil
+	}
+	if err := r.Status().Patch(ctx, kofCluster, client.MergeFrom(original)); err != nil {
+		log.Error(err, "cannot update KofCluster status", "kofClusterName", kofCluster.Name)
+		return err
+	}
+	return nil
+}
+
+func (r *ClusterDeploymentReconciler) setKofClusterStatus(
+	ctx context.Context,
+	kofCluster *kofv1beta1.KofCluster,
+	clusterDeployment *kcmv1beta1.ClusterDeployment,
+	reconcileErr error,
+) error {
+	status := &kofCluster.Status
+	status.ObservedGeneration = clusterDeployment.Generation
+	status.Role = clusterDeployment.Labels[KofClusterRoleLabel]
+	status.RegionalClusterName = ""
+	status.Tenant = ""
+	status.AdditionalRegionalClusterNames = nil
+	status.Endpoints = kofv1beta1.KofClusterEndpoints{}
+
+	switch status.Role {
+	case "child":
+		if err := r.setChildKofClusterStatus(ctx, status, clusterDeployment); err != nil {
+			return err
+		}
+	case "regional":
+		r.setRegionalKofClusterEndpoints(ctx, status, clusterDeployment)
+	}
+
+	if err := r.setIstioKofClusterStatus(ctx, status, clusterDeployment); err != nil {
+		return err
+	}
+
+	if reconcileErr != nil {
+		setKofClusterCondition(
+			kofCluster,
+			kofv1beta1.KofClusterReconciledCondition,
+			metav1.ConditionFalse,
+			"ReconcileFailed",
+			reconcileErr.Error(),
+		)
+	} else {
+		setKofClusterCondition(
+			kofCluster,
+			kofv1beta1.KofClusterReconciledCondition,
+			metav1.ConditionTrue,
+			"Reconciled",
+			"Kof objects of the cluster are reconciled",
+		)
+	}
+	setReadyKofClusterCondition(kofCluster, kofv1beta1.KofClusterReadyCondition, clusterDeployment)
+	// The condition of child clusters is set by the EndpointProber in the background.
+	if r.EndpointProber == nil || status.Role != "child" {
+		meta.RemoveStatusCondition(&status.Conditions, kofv1beta1.KofClusterEndpointsReachableCondition)
+	}
+
+	if status.RegionalClusterName == "" {
+		meta.RemoveStatusCondition(&status.Conditions, kofv1beta1.KofRegionalClusterReadyCondition)
+		return nil
+	}
+	regionalClusterDeployment := &kcmv1beta1.ClusterDeployment{}
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      status.RegionalClusterName,
+		Namespace: clusterDeployment.Namespace,
+	}, regionalClusterDeployment); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		setKofClusterCondition(
+			kofCluster,
+			kofv1beta1.KofRegionalClusterReadyCondition,
+			metav1.ConditionFalse,
+			"RegionalClusterNotFound",
+			"Regional ClusterDeployment is not found",
+		)
+		return nil
+	}
+	setReadyKofClusterCondition(kofCluster, kofv1beta1.KofRegionalClusterReadyCondition, regionalClusterDeployment)
+	return nil
+}
+
+// Reads the regional cluster and the endpoints applied to the child cluster ConfigMap.
+func (r *ClusterDeploymentReconciler) setChildKofClusterStatus(
+	ctx context.Context,
+	status *kofv1beta1.KofClusterStatus,
+	childClusterDeployment *kcmv1beta1.ClusterDeployment,
+) error {
+	configMap := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      "kof-cluster-config-" + childClusterDeployment.Name,
+		Namespace: childClusterDeployment.Namespace,
+	}, configMap); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	status.RegionalClusterName = configMap.Data[RegionalClusterNameKey]
+	status.Tenant = configMap.Data[TenantKey]
+	status.Endpoints = kofv1beta1.KofClusterEndpoints{
+		ReadMetrics:  configMap.Data[ReadMetricsKey],
+		WriteMetrics: configMap.Data[WriteMetricsKey],
+		WriteLogs:    configMap.Data[WriteLogsKey],
+		WriteTraces:  configMap.Data[WriteTracesKey],
+	}
+
+	if data, ok := configMap.Data[AdditionalWriteEndpointsKey]; ok {
+		additionalWriteEndpoints := map[string]AdditionalWriteEndpoints{}
+		if err := yaml.Unmarshal([]byte(data), &additionalWriteEndpoints); err != nil {
+			log.FromContext(ctx).Error(
+				err, "cannot read additional write endpoints",
+				"configMapName", configMap.Name,
+			)
+			return nil
+		}
+		status.AdditionalRegionalClusterNames = slices.Sorted(maps.Keys(additionalWriteEndpoints))
+	}
+	return nil
+}
+
+// Resolves the endpoints of the regional cluster, the invalid ones are left empty.
+func (r *ClusterDeploymentReconciler) setRegionalKofClusterEndpoints(
+	ctx context.Context,
+	status *kofv1beta1.KofClusterStatus,
+	regionalClusterDeployment *kcmv1beta1.ClusterDeployment,
+) {
+	regionalClusterDeploymentConfig, err := ReadClusterDeploymentConfig(getConfigRaw(regionalClusterDeployment))
+	if err != nil || regionalClusterDeploymentConfig == nil {
+		return
+	}
+	endpointTemplates, err := readEndpointTemplates(ctx, r.Client)
+	if err != nil {
+		return
+	}
+
+	getRegionalEndpoint := func(endpointAnnotation string) string {
+		endpoint, err := getEndpoint(
+			ctx,
+			endpointAnnotation,
+			regionalClusterDeployment,
+			regionalClusterDeploymentConfig,
+			endpointTemplates,
+			nil,
+		)
+		if err != nil {
+			return ""
+		}
+		return endpoint
+	}
+	status.Endpoints = kofv1beta1.KofClusterEndpoints{
+		ReadMetrics:  getRegionalEndpoint(ReadMetricsAnnotation),
+		WriteMetrics: getRegionalEndpoint(WriteMetricsAnnotation),
+		ReadLogs:     getRegionalEndpoint(ReadLogsAnnotation),
+		WriteLogs:    getRegionalEndpoint(WriteLogsAnnotation),
+		WriteTraces:  getRegionalEndpoint(WriteTracesAnnotation),
+		ReadTraces:   getRegionalEndpoint(ReadTracesAnnotation),
+	}
+}
+
+func (r *ClusterDeploymentReconciler) setIstioKofClusterStatus(
+	ctx context.Context,
+	status *kofv1beta1.KofClusterStatus,
+	clusterDeployment *kcmv1beta1.ClusterDeployment,
+) error {
+	istioRole, isIstio := clusterDeployment.Labels[IstioRoleLabel]
+	if !isIstio {
+		status.Istio = nil
+		return nil
+	}
+	status.Istio = &kofv1beta1.KofClusterIstioStatus{Role: istioRole}
+	if istioRole != "child" {
+		return nil
+	}
+
+	remoteSecretName := remotesecret.GetRemoteSecretName(clusterDeployment.Name)
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      remoteSecretName,
+		Namespace: istio.IstioSystemNamespace,
+	}, &corev1.Secret{}); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	status.Istio.RemoteSecretName = remoteSecretName
+	return nil
+}
+
+// Mirrors the Ready condition of `clusterDeployment` to the `conditionType` of `kofCluster`.
+func setReadyKofClusterCondition(
+	kofCluster *kofv1beta1.KofCluster,
+	conditionType string,
+	clusterDeployment *kcmv1beta1.ClusterDeployment,
+) {
+	readyCondition := meta.FindStatusCondition(clusterDeployment.Status.Conditions, kcmv1beta1.ReadyCondition)
+	if readyCondition == nil {
+		setKofClusterCondition(
+			kofCluster,
+			conditionType,
+			metav1.ConditionUnknown,
+			"ReadyConditionNotFound",
+			"ClusterDeployment has no Ready condition yet",
+		)
+		return
+	}
+	setKofClusterCondition(kofCluster, conditionType, readyCondition.Status, readyCondition.Reason, readyCondition.Message)
+}
+
+func setKofClusterCondition(
+	kofCluster *kofv1beta1.KofCluster,
+	conditionType string,
+	status metav1.ConditionStatus,
+	reason string,
+	message string,
+) {
+	meta.SetStatusCondition(&kofCluster.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: kofCluster.Status.ObservedGeneration,
+	})
+}
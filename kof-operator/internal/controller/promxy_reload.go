@@ -1,14 +1,207 @@
 package controller
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// +kubebuilder:rbac:groups=discovery.k8s.io,resources=endpointslices,verbs=get;list;watch
+
+const (
+	promxyReloadPath = "/-/reload"
+	// Prometheus API of promxy serving the config it loaded.
+	promxyStatusConfigPath = "/api/v1/status/config"
 )
 
-func ReloadPromxyConfig(endpoint string) error {
-	res, err := http.Post(endpoint, "application/json", strings.NewReader(""))
+// ErrPromxySecretNotServed is returned by `PromxyReloader.Reload` for a config Secret no promxy mounts.
+var ErrPromxySecretNotServed = errors.New("promxy config secret is not mounted by the promxy service")
+
+// ErrPromxyConfigNotLoaded is returned by `PromxyReloader.Reload`
+// while some replica has not loaded the config with the rendered hash yet.
+var ErrPromxyConfigNotLoaded = errors.New("promxy config is not loaded yet")
+
+// PromxyReloader reloads every promxy replica behind a Service mounting the promxy config Secret
+// and confirms each replica loaded the config with the rendered hash.
+type PromxyReloader struct {
+	// Client is used to discover replicas via EndpointSlices of the Service.
+	Client      client.Reader
+	Namespace   string
+	ServiceName string
+	PortName    string
+	// SecretName is the promxy config Secret mounted by the replicas, other Secrets are not reloaded.
+	SecretName string
+	// Endpoint is the reload URL used instead of the replicas if ServiceName is empty.
+	Endpoint   string
+	HTTPClient *http.Client
+	// PropagationDelay is the time for the updated Secret to propagate into the pods.
+	// The configmap-reload sidecar of promxy reloads it on propagation,
+	// replicas not reloaded within this delay are reloaded by the operator.
+	PropagationDelay time.Duration
+}
+
+func NewPromxyReloader(reader client.Reader, namespace, serviceName, secretName, endpoint string) *PromxyReloader {
+	return &PromxyReloader{
+		Client:           reader,
+		Namespace:        namespace,
+		ServiceName:      serviceName,
+		PortName:         "http",
+		SecretName:       secretName,
+		Endpoint:         endpoint,
+		HTTPClient:       &http.Client{Timeout: 5 * time.Second},
+		PropagationDelay: 2 * time.Minute,
+	}
+}
+
+// Reload makes one attempt to confirm that all replicas serving `secretName` loaded the config `configHash`,
+// reloading the replicas that did not after `PropagationDelay` since the Secret was updated at `updatedAt`.
+// Returns `ErrPromxyConfigNotLoaded` if some replica is still to be reloaded, so the caller retries later.
+func (p *PromxyReloader) Reload(ctx context.Context, secretName string, configHash string, updatedAt time.Time) error {
+	if secretName != p.SecretName {
+		return fmt.Errorf("%w: %s", ErrPromxySecretNotServed, secretName)
+	}
+	baseURLs, err := p.getReplicaBaseURLs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(baseURLs) == 0 {
+		return fmt.Errorf("no ready promxy replicas found for service %s/%s", p.Namespace, p.ServiceName)
+	}
+
+	errs := make([]error, len(baseURLs))
+	var wg sync.WaitGroup
+	for i, baseURL := range baseURLs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.reloadReplica(ctx, baseURL, configHash, updatedAt); err != nil {
+				errs[i] = fmt.Errorf("promxy replica %s: %w", baseURL, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (p *PromxyReloader) reloadReplica(ctx context.Context, baseURL string, configHash string, updatedAt time.Time) error {
+	log := log.FromContext(ctx)
+
+	loaded, err := p.hasLoadedConfig(ctx, baseURL, configHash)
+	if err != nil || loaded {
+		return err
+	}
+	if time.Since(updatedAt) < p.PropagationDelay {
+		return ErrPromxyConfigNotLoaded
+	}
+
+	log.Info("Reloading promxy replica", "replica", baseURL)
+	if err := p.post(ctx, baseURL+promxyReloadPath); err != nil {
+		return err
+	}
+	if loaded, err = p.hasLoadedConfig(ctx, baseURL, configHash); err != nil {
+		return err
+	}
+	if !loaded {
+		return ErrPromxyConfigNotLoaded
+	}
+	return nil
+}
+
+// Returns base URLs of ready replicas, or of `Endpoint` if no Service is configured.
+func (p *PromxyReloader) getReplicaBaseURLs(ctx context.Context) ([]string, error) {
+	if p.ServiceName == "" {
+		return []string{strings.TrimSuffix(p.Endpoint, promxyReloadPath)}, nil
+	}
+
+	endpointSlices := &discoveryv1.EndpointSliceList{}
+	if err := p.Client.List(ctx, endpointSlices,
+		client.InNamespace(p.Namespace),
+		client.MatchingLabels{discoveryv1.LabelServiceName: p.ServiceName},
+	); err != nil {
+		return nil, err
+	}
+
+	baseURLs := []string{}
+	for _, endpointSlice := range endpointSlices.Items {
+		port := getEndpointSlicePort(&endpointSlice, p.PortName)
+		if port == 0 {
+			continue
+		}
+		for _, endpoint := range endpointSlice.Endpoints {
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+			for _, address := range endpoint.Addresses {
+				baseURLs = append(baseURLs, "http://"+net.JoinHostPort(address, strconv.Itoa(int(port))))
+			}
+		}
+	}
+	return baseURLs, nil
+}
+
+func getEndpointSlicePort(endpointSlice *discoveryv1.EndpointSlice, name string) int32 {
+	for _, port := range endpointSlice.Ports {
+		if port.Port != nil && port.Name != nil && *port.Name == name {
+			return *port.Port
+		}
+	}
+	return 0
+}
+
+func (p *PromxyReloader) post(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
 	if err != nil {
 		return err
 	}
-	return res.Body.Close()
+	_, err = p.do(req)
+	return err
+}
+
+// Checks the hash marker of the config loaded by the replica, see `RenderPromxyConfig`.
+func (p *PromxyReloader) hasLoadedConfig(ctx context.Context, baseURL string, configHash string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+promxyStatusConfigPath, nil)
+	if err != nil {
+		return false, err
+	}
+	body, err := p.do(req)
+	if err != nil {
+		return false, err
+	}
+	status := struct {
+		Data struct {
+			YAML string `json:"yaml"`
+		} `json:"data"`
+	}{}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return false, fmt.Errorf("cannot parse %s: %w", req.URL, err)
+	}
+	return strings.Contains(status.Data.YAML, promxyConfigHashMarker(configHash)), nil
+}
+
+func (p *PromxyReloader) do(req *http.Request) ([]byte, error) {
+	res, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("%s %s: unexpected status %s: %s", req.Method, req.URL, res.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
 }
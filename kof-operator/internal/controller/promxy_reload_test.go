@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/k0rdent/kof/kof-operator/internal/controller/utils"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	oldConfigHash = "old-config-hash"
+	newConfigHash = "new-config-hash"
+)
+
+// Fake promxy replica serving the hash marker of its loaded config
+// and loading the mounted config on reload.
+type fakePromxyReplica struct {
+	server       *httptest.Server
+	reloadStatus int
+	reloads      atomic.Int32
+	mountedHash  atomic.Value
+	loadedHash   atomic.Value
+}
+
+func newFakePromxyReplica(loadedHash string) *fakePromxyReplica {
+	replica := &fakePromxyReplica{reloadStatus: http.StatusOK}
+	replica.mountedHash.Store(newConfigHash)
+	replica.loadedHash.Store(loadedHash)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+promxyReloadPath, func(w http.ResponseWriter, r *http.Request) {
+		replica.reloads.Add(1)
+		if replica.reloadStatus == http.StatusOK {
+			replica.loadedHash.Store(replica.mountedHash.Load())
+		}
+		w.WriteHeader(replica.reloadStatus)
+	})
+	mux.HandleFunc("GET "+promxyStatusConfigPath, func(w http.ResponseWriter, r *http.Request) {
+		config := fmt.Sprintf("alerting:\n  alert_relabel_configs:\n  - regex: %s\n    action: labeldrop\n",
+			promxyConfigHashMarker(replica.loadedHash.Load().(string)))
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"status": "success",
+			"data":   map[string]string{"yaml": config},
+		})
+	})
+	replica.server = httptest.NewServer(mux)
+	return replica
+}
+
+func (r *fakePromxyReplica) hostPort() (string, int32) {
+	u, err := url.Parse(r.server.URL)
+	Expect(err).NotTo(HaveOccurred())
+	host, port, err := net.SplitHostPort(u.Host)
+	Expect(err).NotTo(HaveOccurred())
+	portNumber, err := strconv.Atoi(port)
+	Expect(err).NotTo(HaveOccurred())
+	return host, int32(portNumber)
+}
+
+var _ = Describe("Promxy reload", func() {
+	ctx := context.Background()
+	const secretName = "test-promxy-config"
+
+	newReloader := func(reader client.Reader, serviceName, endpoint string) *PromxyReloader {
+		reloader := NewPromxyReloader(reader, "default", serviceName, secretName, endpoint)
+		reloader.PropagationDelay = time.Minute
+		return reloader
+	}
+
+	It("should confirm the replica reloaded by its sidecar without reloading it", func() {
+		replica := newFakePromxyReplica(newConfigHash)
+		defer replica.server.Close()
+
+		reloader := newReloader(k8sClient, "", replica.server.URL+promxyReloadPath)
+		Expect(reloader.Reload(ctx, secretName, newConfigHash, time.Now().Add(-time.Second))).To(Succeed())
+		Expect(replica.reloads.Load()).To(BeZero())
+	})
+
+	It("should wait for the updated secret to propagate", func() {
+		replica := newFakePromxyReplica(oldConfigHash)
+		defer replica.server.Close()
+
+		reloader := newReloader(k8sClient, "", replica.server.URL+promxyReloadPath)
+		Expect(reloader.Reload(ctx, secretName, newConfigHash, time.Now())).To(MatchError(ErrPromxyConfigNotLoaded))
+		Expect(replica.reloads.Load()).To(BeZero())
+	})
+
+	It("should reload the replica not reloaded after the propagation delay", func() {
+		replica := newFakePromxyReplica(oldConfigHash)
+		defer replica.server.Close()
+
+		reloader := newReloader(k8sClient, "", replica.server.URL+promxyReloadPath)
+		Expect(reloader.Reload(ctx, secretName, newConfigHash, time.Now().Add(-2*time.Minute))).To(Succeed())
+		Expect(replica.reloads.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should not confirm the replica reloaded with the config not propagated yet", func() {
+		replica := newFakePromxyReplica(oldConfigHash)
+		replica.mountedHash.Store(oldConfigHash)
+		defer replica.server.Close()
+
+		reloader := newReloader(k8sClient, "", replica.server.URL+promxyReloadPath)
+		Expect(reloader.Reload(ctx, secretName, newConfigHash, time.Now().Add(-2*time.Minute))).To(
+			MatchError(ErrPromxyConfigNotLoaded),
+		)
+		Expect(replica.reloads.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should fail on non-2xx reload responses", func() {
+		replica := newFakePromxyReplica(oldConfigHash)
+		replica.reloadStatus = http.StatusInternalServerError
+		defer replica.server.Close()
+
+		reloader := newReloader(k8sClient, "", replica.server.URL+promxyReloadPath)
+		Expect(reloader.Reload(ctx, secretName, newConfigHash, time.Now().Add(-2*time.Minute))).To(
+			MatchError(ContainSubstring("500")),
+		)
+		Expect(replica.reloads.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should not reload secrets not mounted by promxy", func() {
+		replica := newFakePromxyReplica(oldConfigHash)
+		defer replica.server.Close()
+
+		reloader := newReloader(k8sClient, "", replica.server.URL+promxyReloadPath)
+		Expect(reloader.Reload(ctx, "other-promxy-config", newConfigHash, time.Now())).To(MatchError(ErrPromxySecretNotServed))
+		Expect(replica.reloads.Load()).To(BeZero())
+	})
+
+	It("should reload all ready replicas behind the service", func() {
+		const serviceName = "test-promxy"
+		reloaded := newFakePromxyReplica(newConfigHash)
+		stale := newFakePromxyReplica(oldConfigHash)
+		replicas := []*fakePromxyReplica{reloaded, stale}
+		notReady := newFakePromxyReplica(oldConfigHash)
+		for _, replica := range append(replicas, notReady) {
+			defer replica.server.Close()
+		}
+
+		portName := "http"
+		protocol := corev1.ProtocolTCP
+		// The API server rejects loopback addresses of the fake replicas.
+		fakeClientBuilder := fake.NewClientBuilder().WithScheme(k8sClient.Scheme())
+		for i, replica := range append(replicas, notReady) {
+			host, port := replica.hostPort()
+			endpointSlice := &discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("%s-%d", serviceName, i),
+					Namespace: "default",
+					Labels:    map[string]string{discoveryv1.LabelServiceName: serviceName},
+				},
+				AddressType: discoveryv1.AddressTypeIPv4,
+				Endpoints: []discoveryv1.Endpoint{{
+					Addresses:  []string{host},
+					Conditions: discoveryv1.EndpointConditions{Ready: utils.BoolPtr(replica != notReady)},
+				}},
+				Ports: []discoveryv1.EndpointPort{{
+					Name:     &portName,
+					Port:     &port,
+					Protocol: &protocol,
+				}},
+			}
+			fakeClientBuilder.WithObjects(endpointSlice)
+		}
+
+		reloader := newReloader(fakeClientBuilder.Build(), serviceName, "")
+		Expect(reloader.Reload(ctx, secretName, newConfigHash, time.Now().Add(-2*time.Minute))).To(Succeed())
+		Expect(reloaded.reloads.Load()).To(BeZero())
+		Expect(stale.reloads.Load()).To(BeEquivalentTo(1))
+		Expect(notReady.reloads.Load()).To(BeZero())
+	})
+
+	It("should fail if no replicas are ready", func() {
+		reloader := newReloader(k8sClient, "missing-promxy", "")
+		Expect(reloader.Reload(ctx, secretName, newConfigHash, time.Now())).To(
+			MatchError(ContainSubstring("no ready promxy replicas")),
+		)
+	})
+})
@@ -18,15 +18,27 @@ package controller
 
 import (
 	"context"
+	stderrors "errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
 
+	"github.com/prometheus/common/model"
 	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	kofv1beta1 "github.com/k0rdent/kof/kof-operator/api/v1beta1"
 	"github.com/k0rdent/kof/kof-operator/internal/controller/utils"
@@ -34,7 +46,22 @@ import (
 
 const PromxySecretNameLabel = "k0rdent.mirantis.com/promxy-secret-name"
 
-type PromxyConfigReloadFunc func() error
+// PromxyConfigSecretLabel marks promxy config Secrets rendered by the operator,
+// so they are rewritten even after the last server group is deleted.
+const PromxyConfigSecretLabel = "k0rdent.mirantis.com/kof-promxy-config"
+
+// PromxyReloadedConfigHashAnnotation of the promxy config Secret is the hash
+// of the config promxy was last reloaded with, so unchanged config is not reloaded again.
+const PromxyReloadedConfigHashAnnotation = "k0rdent.mirantis.com/kof-promxy-reloaded-config-hash"
+
+// PromxyConfigUpdatedAtAnnotation of the promxy config Secret is the time its config was last updated,
+// promxy is expected to reload it after this time.
+const PromxyConfigUpdatedAtAnnotation = "k0rdent.mirantis.com/kof-promxy-config-updated-at"
+
+// PromxyConfigReloadFunc makes one attempt to reload promxy serving the config Secret `secretName`
+// updated at `updatedAt` and confirm it loaded the config `configHash`.
+// It returns `ErrPromxyConfigNotLoaded` to be retried later, or `ErrPromxySecretNotServed`.
+type PromxyConfigReloadFunc func(ctx context.Context, secretName string, configHash string, updatedAt time.Time) error
 
 // PromxyServerGroupReconciler reconciles a PromxyServerGroup object
 type PromxyServerGroupReconciler struct {
@@ -47,6 +74,7 @@ type PromxyServerGroupReconciler struct {
 // +kubebuilder:rbac:groups=kof.k0rdent.mirantis.com,resources=promxyservergroups,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=kof.k0rdent.mirantis.com,resources=promxyservergroups/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=kof.k0rdent.mirantis.com,resources=promxyservergroups/finalizers,verbs=update
+// +kubebuilder:rbac:groups=kof.k0rdent.mirantis.com,resources=promxyconfigs,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -76,6 +104,13 @@ func (r *PromxyServerGroupReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		name, ok := promxyServerGroup.Labels[PromxySecretNameLabel]
 		if !ok {
 			log.Info("Skipping promxyServerGroup that doesn't have secret name label", "promxyServerGroup", promxyServerGroup)
+			if err := r.patchStatus(ctx, []*kofv1beta1.PromxyServerGroup{&promxyServerGroup}, func(group *kofv1beta1.PromxyServerGroup) {
+				group.Status.SecretName = ""
+				setServerGroupCondition(group, kofv1beta1.RenderedCondition, metav1.ConditionFalse, "SecretNameLabelMissing",
+					"Label "+PromxySecretNameLabel+" is not set")
+			}); err != nil {
+				return ctrl.Result{}, err
+			}
 			continue
 		}
 		groups, ok := promxyServerGroupsBySecretName[name]
@@ -86,102 +121,174 @@ func (r *PromxyServerGroupReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		promxyServerGroupsBySecretName[name] = groups
 	}
 
+	promxySecretsList := &coreV1.SecretList{}
+	if err := r.List(ctx, promxySecretsList, client.InNamespace(req.Namespace), client.MatchingLabels{
+		PromxyConfigSecretLabel: "true",
+	}); err != nil {
+		log.Error(err, "cannot get promxy config secret list")
+		return ctrl.Result{}, err
+	}
+	for _, promxySecret := range promxySecretsList.Items {
+		if _, ok := promxyServerGroupsBySecretName[promxySecret.Name]; !ok {
+			log.Info("Removing all server groups from promxy config secret", "secretName", promxySecret.Name)
+			promxyServerGroupsBySecretName[promxySecret.Name] = []*kofv1beta1.PromxyServerGroup{}
+		}
+	}
+
+	promxyConfigsList := &kofv1beta1.PromxyConfigList{}
+	if err := r.List(ctx, promxyConfigsList, client.InNamespace(req.Namespace)); err != nil {
+		log.Error(err, "cannot get promxy config list")
+		return ctrl.Result{}, err
+	}
+	for _, promxyConfig := range promxyConfigsList.Items {
+		if _, ok := promxyServerGroupsBySecretName[promxyConfig.Name]; !ok {
+			promxyServerGroupsBySecretName[promxyConfig.Name] = []*kofv1beta1.PromxyServerGroup{}
+		}
+	}
+
 	log.Info("Processing promxy server groups", "promxyServerGroupsBySecretName", promxyServerGroupsBySecretName)
 
-	for name, groups := range promxyServerGroupsBySecretName {
-		secretTemplateData := &PromxyConfig{
-			RemoteWriteUrl: r.RemoteWriteUrl,
-			ServerGroups:   make([]*PromxyConfigServerGroup, 0),
-		}
-		for _, group := range groups {
-			credentialsSecret := &coreV1.Secret{}
-			basicAuthEnabled := group.Spec.HttpClient.BasicAuth.CredentialsSecretName != ""
-			if basicAuthEnabled {
-				if err := r.Get(ctx, types.NamespacedName{
-					Name:      group.Spec.HttpClient.BasicAuth.CredentialsSecretName,
-					Namespace: req.Namespace,
-				}, credentialsSecret); err != nil {
-					log.Error(err, "cannot read auth credentials secret")
-					return ctrl.Result{}, err
-				}
-			}
-			secretTemplateData.ServerGroups = append(secretTemplateData.ServerGroups, &PromxyConfigServerGroup{
-				Targets:               group.Spec.Targets,
-				PathPrefix:            group.Spec.PathPrefix,
-				Scheme:                group.Spec.Scheme,
-				DialTimeout:           group.Spec.HttpClient.DialTimeout.Duration.String(),
-				TlsInsecureSkipVerify: group.Spec.HttpClient.TLSConfig.InsecureSkipVerify,
-				BasicAuthEnabled:      basicAuthEnabled,
-				Username:              string(credentialsSecret.Data[group.Spec.HttpClient.BasicAuth.UsernameKey]),
-				Password:              string(credentialsSecret.Data[group.Spec.HttpClient.BasicAuth.PasswordKey]),
-				ClusterName:           group.Spec.ClusterName,
-			})
+	// A broken Secret should not block the others, so all of them are reconciled in a stable order.
+	// A pending reload is returned as an error too, so it is retried with the backoff of the controller.
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(promxyServerGroupsBySecretName)) {
+		if err := r.reconcilePromxySecret(ctx, req.Namespace, name, promxyServerGroupsBySecretName[name]); err != nil {
+			errs = append(errs, fmt.Errorf("promxy config secret %s: %w", name, err))
+		}
+	}
+	if err := stderrors.Join(errs...); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// Renders `groups` into the promxy config Secret `name`, reloads promxy if the config changed,
+// and reports the outcome in the status of each group.
+// Returns `ErrPromxyConfigNotLoaded` if the reload is not confirmed yet and should be checked again later.
+func (r *PromxyServerGroupReconciler) reconcilePromxySecret(
+	ctx context.Context,
+	namespace string,
+	name string,
+	groups []*kofv1beta1.PromxyServerGroup,
+) error {
+	log := log.FromContext(ctx)
+
+	// The webhook rejects Secrets promxy does not mount, groups labeled before are excluded as invalid.
+	if servedName := GetServedPromxySecretName(); name != servedName && len(groups) > 0 {
+		notServedErr := fmt.Errorf("%w: %s, use %s", ErrPromxySecretNotServed, name, servedName)
+		log.Info("Skipping promxy server groups of not served secret", "secretName", name)
+		if err := r.patchStatus(ctx, groups, func(group *kofv1beta1.PromxyServerGroup) {
+			setServerGroupCondition(group, kofv1beta1.RenderedCondition, metav1.ConditionFalse, "SecretNotServed", notServedErr.Error())
+			meta.RemoveStatusCondition(&group.Status.Conditions, kofv1beta1.ReloadedCondition)
+		}); err != nil {
+			return err
 		}
-		data, err := RenderPromxySecretTemplate(secretTemplateData)
+		groups = nil
+	}
+
+	promxyConfig, err := r.getPromxyConfig(ctx, namespace, name)
+	if err != nil {
+		log.Error(err, "cannot read promxy global config", "promxyConfig", name)
+		_ = r.patchStatus(ctx, groups, func(group *kofv1beta1.PromxyServerGroup) {
+			setServerGroupCondition(group, kofv1beta1.RenderedCondition, metav1.ConditionFalse, "PromxyConfigReadFailed", err.Error())
+		})
+		return err
+	}
+	validGroups := make([]*kofv1beta1.PromxyServerGroup, 0, len(groups))
+	for _, group := range groups {
+		serverGroup, err := r.getPromxyConfigServerGroup(ctx, namespace, group)
 		if err != nil {
-			log.Error(err, "cannot render promxy secret template")
-			return ctrl.Result{}, err
+			log.Error(err, "cannot read promxy server group credentials", "promxyServerGroup", group.Name)
+			_ = r.patchStatus(ctx, []*kofv1beta1.PromxyServerGroup{group}, func(group *kofv1beta1.PromxyServerGroup) {
+				setServerGroupCondition(group, kofv1beta1.CredentialsResolvedCondition, metav1.ConditionFalse, "CredentialsSecretReadFailed", err.Error())
+			})
+			return err
 		}
-		secret := &coreV1.Secret{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      req.Name,
-				Namespace: req.Namespace,
-			},
+		if err := r.patchStatus(ctx, []*kofv1beta1.PromxyServerGroup{group}, func(group *kofv1beta1.PromxyServerGroup) {
+			setServerGroupCondition(group, kofv1beta1.CredentialsResolvedCondition, metav1.ConditionTrue, "CredentialsResolved", "")
+		}); err != nil {
+			return err
 		}
-		err = r.Get(ctx, types.NamespacedName{
-			Name:      name,
-			Namespace: req.Namespace,
-		}, secret)
-		if err != nil && errors.IsNotFound(err) {
-			secret.ObjectMeta = metav1.ObjectMeta{
-				Name:      name,
-				Namespace: req.Namespace,
-			}
-			setSecretOperatorLabels(secret)
-			secret.StringData = map[string]string{
-				"config.yaml": data,
-			}
-			log.Info("Creating promxy config secret", "secretName", name)
-			if err := r.Create(ctx, secret); err != nil {
-				utils.LogEvent(
-					ctx,
-					"PromxySecretCreationFailed",
-					"Cannot create promxy secret",
-					secret,
-					err,
-					"promxySecretName", secret.Name,
-				)
-				return ctrl.Result{}, err
-			}
-			log.Info("Reloading promxy config")
-			if err := r.PromxyConfigReload(); err != nil {
-				utils.LogEvent(
-					ctx,
-					"PromxyConfigReloadingFailed",
-					"Cannot reload promxy config",
-					secret,
-					err,
-					"promxySecretName", secret.Name,
-				)
-				return ctrl.Result{}, err
+
+		// Invalid group is excluded instead of breaking the config of other groups.
+		if validationErr := ValidatePromxyServerGroup(serverGroup); validationErr != nil {
+			log.Info("Skipping invalid promxy server group", "promxyServerGroup", group.Name, "error", validationErr.Error())
+			if err := r.patchStatus(ctx, []*kofv1beta1.PromxyServerGroup{group}, func(group *kofv1beta1.PromxyServerGroup) {
+				setServerGroupCondition(group, kofv1beta1.RenderedCondition, metav1.ConditionFalse, "InvalidConfig", validationErr.Error())
+			}); err != nil {
+				return err
 			}
 			continue
 		}
-		if err != nil {
+		promxyConfig.Promxy.ServerGroups = append(promxyConfig.Promxy.ServerGroups, serverGroup)
+		validGroups = append(validGroups, group)
+	}
+	groups = validGroups
+
+	data, configHash, err := RenderPromxyConfig(promxyConfig)
+	if err != nil {
+		log.Error(err, "cannot render promxy config")
+		_ = r.patchStatus(ctx, groups, func(group *kofv1beta1.PromxyServerGroup) {
+			setServerGroupCondition(group, kofv1beta1.RenderedCondition, metav1.ConditionFalse, "RenderFailed", err.Error())
+		})
+		return err
+	}
+	if err := ValidatePromxyConfig(data); err != nil {
+		// Retrying would not help until the input changes and triggers a new reconcile.
+		log.Error(err, "rendered promxy config is invalid", "secretName", name)
+		return r.patchStatus(ctx, groups, func(group *kofv1beta1.PromxyServerGroup) {
+			setServerGroupCondition(group, kofv1beta1.RenderedCondition, metav1.ConditionFalse, "InvalidConfig", err.Error())
+		})
+	}
+
+	secret := &coreV1.Secret{}
+	err = r.Get(ctx, types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	}, secret)
+	if err != nil && errors.IsNotFound(err) {
+		secret.ObjectMeta = metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		}
+		setSecretOperatorLabels(secret)
+		setSecretConfigData(secret, data)
+		log.Info("Creating promxy config secret", "secretName", name)
+		if err := r.Create(ctx, secret); err != nil {
 			utils.LogEvent(
 				ctx,
-				"PromxySecretNotFound",
-				"Cannot get promxy secret",
+				"PromxySecretCreationFailed",
+				"Cannot create promxy secret",
 				secret,
 				err,
 				"promxySecretName", secret.Name,
 			)
-			return ctrl.Result{}, err
+			_ = r.patchStatus(ctx, groups, func(group *kofv1beta1.PromxyServerGroup) {
+				setServerGroupCondition(group, kofv1beta1.RenderedCondition, metav1.ConditionFalse, "SecretCreationFailed", err.Error())
+			})
+			return err
 		}
+	} else if err != nil {
+		secret.Name = name
+		secret.Namespace = namespace
+		utils.LogEvent(
+			ctx,
+			"PromxySecretNotFound",
+			"Cannot get promxy secret",
+			secret,
+			err,
+			"promxySecretName", secret.Name,
+		)
+		_ = r.patchStatus(ctx, groups, func(group *kofv1beta1.PromxyServerGroup) {
+			setServerGroupCondition(group, kofv1beta1.RenderedCondition, metav1.ConditionFalse, "SecretReadFailed", err.Error())
+		})
+		return err
+	} else if string(secret.Data["config.yaml"]) == data && hasSecretOperatorLabels(secret) {
+		log.Info("Promxy config secret is up to date", "secretName", name)
+	} else {
 		setSecretOperatorLabels(secret)
-		secret.StringData = map[string]string{
-			"config.yaml": data,
-		}
+		setSecretConfigData(secret, data)
 		log.Info("Updating promxy config secret", "secretName", name)
 		if err := r.Update(ctx, secret); err != nil {
 			utils.LogEvent(
@@ -192,32 +299,580 @@ func (r *PromxyServerGroupReconciler) Reconcile(ctx context.Context, req ctrl.Re
 				err,
 				"promxySecretName", secret.Name,
 			)
-			return ctrl.Result{}, err
+			_ = r.patchStatus(ctx, groups, func(group *kofv1beta1.PromxyServerGroup) {
+				setServerGroupCondition(group, kofv1beta1.RenderedCondition, metav1.ConditionFalse, "SecretUpdateFailed", err.Error())
+			})
+			return err
 		}
-		log.Info("Reloading promxy config")
-		if err := r.PromxyConfigReload(); err != nil {
-			utils.LogEvent(
-				ctx,
-				"PromxySecretReloadFailed",
-				"Cannot reload promxy config",
-				secret,
-				err,
-				"promxySecretName", secret.Name,
-			)
-			return ctrl.Result{}, err
+	}
+	if err := r.patchStatus(ctx, groups, func(group *kofv1beta1.PromxyServerGroup) {
+		group.Status.ObservedGeneration = group.Generation
+		group.Status.SecretName = name
+		group.Status.ConfigHash = configHash
+		setServerGroupCondition(group, kofv1beta1.RenderedCondition, metav1.ConditionTrue, "SecretUpdated", "")
+	}); err != nil {
+		return err
+	}
+
+	if secret.Annotations[PromxyReloadedConfigHashAnnotation] == configHash {
+		log.Info("Promxy is already reloaded with this config", "secretName", name)
+		return r.patchStatus(ctx, groups, func(group *kofv1beta1.PromxyServerGroup) {
+			setServerGroupCondition(group, kofv1beta1.ReloadedCondition, metav1.ConditionTrue, "Reloaded", "")
+		})
+	}
+
+	log.Info("Reloading promxy config", "secretName", name)
+	err = r.PromxyConfigReload(ctx, name, configHash, getSecretConfigUpdatedAt(secret))
+	if stderrors.Is(err, ErrPromxySecretNotServed) {
+		log.Info("Promxy config secret is not served by promxy, not reloading it", "secretName", name)
+		return nil
+	}
+	if stderrors.Is(err, ErrPromxyConfigNotLoaded) {
+		log.Info("Promxy config reload is pending", "secretName", name, "error", err.Error())
+		if patchErr := r.patchStatus(ctx, groups, func(group *kofv1beta1.PromxyServerGroup) {
+			setServerGroupCondition(group, kofv1beta1.ReloadedCondition, metav1.ConditionFalse, "ReloadPending", err.Error())
+		}); patchErr != nil {
+			return patchErr
 		}
+		return err
+	}
+	if err != nil {
+		utils.LogEvent(
+			ctx,
+			"PromxyConfigReloadingFailed",
+			"Cannot reload promxy config",
+			secret,
+			err,
+			"promxySecretName", secret.Name,
+		)
+		_ = r.patchStatus(ctx, groups, func(group *kofv1beta1.PromxyServerGroup) {
+			setServerGroupCondition(group, kofv1beta1.ReloadedCondition, metav1.ConditionFalse, "ReloadFailed", err.Error())
+		})
+		return err
 	}
+	original := secret.DeepCopy()
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[PromxyReloadedConfigHashAnnotation] = configHash
+	if err := r.Patch(ctx, secret, client.MergeFrom(original)); err != nil {
+		log.Error(err, "cannot annotate promxy config secret", "secretName", name)
+		return err
+	}
+	return r.patchStatus(ctx, groups, func(group *kofv1beta1.PromxyServerGroup) {
+		setServerGroupCondition(group, kofv1beta1.ReloadedCondition, metav1.ConditionTrue, "Reloaded", "")
+	})
+}
 
-	return ctrl.Result{}, nil
+// Returns the promxy config with the global settings of the PromxyConfig `name`,
+// or with the defaults if it does not exist.
+func (r *PromxyServerGroupReconciler) getPromxyConfig(
+	ctx context.Context,
+	namespace string,
+	name string,
+) (*PromxyConfig, error) {
+	promxyConfig := NewPromxyConfig(r.RemoteWriteUrl)
+	globalConfig := &kofv1beta1.PromxyConfig{}
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	}, globalConfig); err != nil {
+		if errors.IsNotFound(err) {
+			return promxyConfig, nil
+		}
+		return nil, err
+	}
+
+	spec := &globalConfig.Spec
+	if spec.EvaluationInterval.Duration != 0 {
+		promxyConfig.Global.EvaluationInterval = model.Duration(spec.EvaluationInterval.Duration).String()
+	}
+	if spec.ExternalLabels != nil {
+		promxyConfig.Global.ExternalLabels = spec.ExternalLabels
+	}
+	if len(spec.RemoteWrite) > 0 {
+		promxyConfig.RemoteWrite = make([]*PromxyRemoteWriteConfig, 0, len(spec.RemoteWrite))
+		for i, remoteWrite := range spec.RemoteWrite {
+			httpClient, err := r.getPromxyHTTPClientConfig(ctx, namespace, getHTTPClientConfig(&remoteWrite.HTTPAuthConfig))
+			if err != nil {
+				return nil, fmt.Errorf("remote_write[%d]: %w", i, err)
+			}
+			promxyConfig.RemoteWrite = append(promxyConfig.RemoteWrite, &PromxyRemoteWriteConfig{
+				URL:                    remoteWrite.URL,
+				PromxyHTTPClientConfig: *httpClient,
+			})
+		}
+	}
+	if len(spec.Alertmanagers) > 0 {
+		promxyConfig.Alerting.Alertmanagers = make([]*PromxyAlertmanagerConfig, 0, len(spec.Alertmanagers))
+		for i, alertmanager := range spec.Alertmanagers {
+			httpClient, err := r.getPromxyHTTPClientConfig(ctx, namespace, getHTTPClientConfig(&alertmanager.HTTPAuthConfig))
+			if err != nil {
+				return nil, fmt.Errorf("alertmanagers[%d]: %w", i, err)
+			}
+			scheme := alertmanager.Scheme
+			if scheme == "" {
+				scheme = "http"
+			}
+			promxyConfig.Alerting.Alertmanagers = append(promxyConfig.Alerting.Alertmanagers, &PromxyAlertmanagerConfig{
+				Scheme:                 scheme,
+				PathPrefix:             alertmanager.PathPrefix,
+				StaticConfigs:          []*PromxyStaticConfig{{Targets: alertmanager.Targets}},
+				PromxyHTTPClientConfig: *httpClient,
+			})
+		}
+	}
+	return promxyConfig, nil
+}
+
+// Returns the http client config with TLS and auth of `auth` to resolve it like the one of a server group.
+func getHTTPClientConfig(auth *kofv1beta1.HTTPAuthConfig) *kofv1beta1.HTTPClientConfig {
+	return &kofv1beta1.HTTPClientConfig{
+		TLSConfig:   auth.TLSConfig,
+		BasicAuth:   auth.BasicAuth,
+		BearerToken: auth.BearerToken,
+		OAuth2:      auth.OAuth2,
+	}
+}
+
+// Resolves credentials and TLS materials referenced by `group`
+// into the server group of the promxy config.
+func (r *PromxyServerGroupReconciler) getPromxyConfigServerGroup(
+	ctx context.Context,
+	namespace string,
+	group *kofv1beta1.PromxyServerGroup,
+) (*PromxyServerGroupConfig, error) {
+	httpClient := &group.Spec.HttpClient
+	serverGroup := &PromxyServerGroupConfig{
+		PathPrefix:     group.Spec.PathPrefix,
+		Scheme:         group.Spec.Scheme,
+		Labels:         map[string]string{},
+		QueryParams:    group.Spec.QueryParams,
+		RemoteRead:     group.Spec.RemoteRead,
+		RemoteReadPath: group.Spec.RemoteReadPath,
+		IgnoreError:    group.Spec.IgnoreError == nil || *group.Spec.IgnoreError,
+	}
+	maps.Copy(serverGroup.Labels, group.Spec.Labels)
+	serverGroup.Labels["promxyCluster"] = group.Spec.ClusterName
+	for _, relabelConfig := range group.Spec.RelabelConfigs {
+		serverGroup.RelabelConfigs = append(serverGroup.RelabelConfigs, &PromxyRelabelConfig{
+			SourceLabels: relabelConfig.SourceLabels,
+			Separator:    relabelConfig.Separator,
+			Regex:        relabelConfig.Regex,
+			Modulus:      relabelConfig.Modulus,
+			TargetLabel:  relabelConfig.TargetLabel,
+			Replacement:  relabelConfig.Replacement,
+			Action:       relabelConfig.Action,
+		})
+	}
+	if group.Spec.AntiAffinity.Duration != 0 {
+		serverGroup.AntiAffinity = group.Spec.AntiAffinity.Duration.String()
+	}
+	if group.Spec.Timeout.Duration != 0 {
+		serverGroup.Timeout = group.Spec.Timeout.Duration.String()
+	}
+	if len(group.Spec.Targets) > 0 {
+		serverGroup.StaticConfigs = []*PromxyStaticConfig{{Targets: group.Spec.Targets}}
+	}
+	for _, dnsSDConfig := range group.Spec.DNSSDConfigs {
+		serverGroup.DNSSDConfigs = append(serverGroup.DNSSDConfigs, getPromxyDNSSDConfig(&dnsSDConfig))
+	}
+	for _, kubernetesSDConfig := range group.Spec.KubernetesSDConfigs {
+		serverGroup.KubernetesSDConfigs = append(serverGroup.KubernetesSDConfigs, getPromxyKubernetesSDConfig(&kubernetesSDConfig))
+	}
+
+	promxyHTTPClient, err := r.getPromxyHTTPClientConfig(ctx, namespace, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	serverGroup.HTTPClient = *promxyHTTPClient
+	serverGroup.HTTPClient.DialTimeout = httpClient.DialTimeout.Duration.String()
+	return serverGroup, nil
 }
 
+// Checks `httpClient` can be resolved into a promxy http client config,
+// used both at admission and before resolving it.
+func validateHTTPClientConfig(httpClient *kofv1beta1.HTTPClientConfig) error {
+	tlsConfig := &httpClient.TLSConfig
+	for i, source := range []*kofv1beta1.SecretOrConfigMap{tlsConfig.CA, tlsConfig.Cert} {
+		if source != nil && (source.Secret == nil) == (source.ConfigMap == nil) {
+			name := []string{"tls_config.ca", "tls_config.cert"}[i]
+			return fmt.Errorf("exactly one of %s.secret and %s.config_map should be set", name, name)
+		}
+	}
+	if (tlsConfig.Cert == nil) != (tlsConfig.KeySecret == nil) {
+		return fmt.Errorf("both tls_config.cert and tls_config.key_secret should be set for mTLS")
+	}
+
+	authMethods := 0
+	if httpClient.BasicAuth.CredentialsSecretName != "" {
+		authMethods++
+	}
+	if bearerToken := httpClient.BearerToken; bearerToken != nil {
+		authMethods++
+		if (bearerToken.Secret == nil) == (bearerToken.File == "") {
+			return fmt.Errorf("exactly one of bearer_token.secret and bearer_token.file should be set")
+		}
+	}
+	if httpClient.OAuth2 != nil {
+		authMethods++
+	}
+	if authMethods > 1 {
+		return fmt.Errorf("at most one of basic_auth, bearer_token and oauth2 should be set")
+	}
+	return nil
+}
+
+// Resolves credentials and TLS materials referenced by `httpClient`.
+func (r *PromxyServerGroupReconciler) getPromxyHTTPClientConfig(
+	ctx context.Context,
+	namespace string,
+	httpClient *kofv1beta1.HTTPClientConfig,
+) (*PromxyHTTPClientConfig, error) {
+	if err := validateHTTPClientConfig(httpClient); err != nil {
+		return nil, err
+	}
+	var err error
+	promxyHTTPClient := &PromxyHTTPClientConfig{}
+	tlsConfig := &httpClient.TLSConfig
+	promxyTLSConfig := &PromxyTLSConfig{
+		InsecureSkipVerify: tlsConfig.InsecureSkipVerify,
+		ServerName:         tlsConfig.ServerName,
+	}
+	if promxyTLSConfig.CA, err = r.getSecretOrConfigMapValue(ctx, namespace, tlsConfig.CA); err != nil {
+		return nil, err
+	}
+	if promxyTLSConfig.Cert, err = r.getSecretOrConfigMapValue(ctx, namespace, tlsConfig.Cert); err != nil {
+		return nil, err
+	}
+	if promxyTLSConfig.Key, err = r.getSecretValue(ctx, namespace, tlsConfig.KeySecret); err != nil {
+		return nil, err
+	}
+	if (promxyTLSConfig.Cert == "") != (promxyTLSConfig.Key == "") {
+		return nil, fmt.Errorf("both tls_config.cert and tls_config.key_secret should be set for mTLS")
+	}
+	if *promxyTLSConfig != (PromxyTLSConfig{}) {
+		promxyHTTPClient.TLSConfig = promxyTLSConfig
+	}
+
+	if basicAuth := &httpClient.BasicAuth; basicAuth.CredentialsSecretName != "" {
+		credentialsSecret := &coreV1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{
+			Name:      basicAuth.CredentialsSecretName,
+			Namespace: namespace,
+		}, credentialsSecret); err != nil {
+			return nil, err
+		}
+		promxyHTTPClient.BasicAuth = &PromxyBasicAuth{
+			Username: string(credentialsSecret.Data[basicAuth.UsernameKey]),
+			Password: string(credentialsSecret.Data[basicAuth.PasswordKey]),
+		}
+	}
+	if bearerToken := httpClient.BearerToken; bearerToken != nil {
+		authorization := &PromxyAuthorization{
+			Type:            "Bearer",
+			CredentialsFile: bearerToken.File,
+		}
+		if authorization.Credentials, err = r.getSecretValue(ctx, namespace, bearerToken.Secret); err != nil {
+			return nil, err
+		}
+		promxyHTTPClient.Authorization = authorization
+	}
+	if oauth2 := httpClient.OAuth2; oauth2 != nil {
+		promxyOAuth2 := &PromxyOAuth2{
+			ClientID:       oauth2.ClientID,
+			TokenURL:       oauth2.TokenURL,
+			Scopes:         oauth2.Scopes,
+			EndpointParams: oauth2.EndpointParams,
+		}
+		if promxyOAuth2.ClientSecret, err = r.getSecretValue(ctx, namespace, &oauth2.ClientSecret); err != nil {
+			return nil, err
+		}
+		promxyHTTPClient.OAuth2 = promxyOAuth2
+	}
+	return promxyHTTPClient, nil
+}
+
+func getPromxyDNSSDConfig(dnsSDConfig *kofv1beta1.DNSSDConfig) *PromxyDNSSDConfig {
+	promxyDNSSDConfig := &PromxyDNSSDConfig{
+		Names: dnsSDConfig.Names,
+		Type:  dnsSDConfig.Type,
+		Port:  dnsSDConfig.Port,
+	}
+	if dnsSDConfig.RefreshInterval.Duration != 0 {
+		promxyDNSSDConfig.RefreshInterval = dnsSDConfig.RefreshInterval.Duration.String()
+	}
+	return promxyDNSSDConfig
+}
+
+func getPromxyKubernetesSDConfig(kubernetesSDConfig *kofv1beta1.KubernetesSDConfig) *PromxyKubernetesSDConfig {
+	promxyKubernetesSDConfig := &PromxyKubernetesSDConfig{
+		Role:           kubernetesSDConfig.Role,
+		APIServer:      kubernetesSDConfig.APIServer,
+		KubeconfigFile: kubernetesSDConfig.KubeconfigFile,
+	}
+	if len(kubernetesSDConfig.Namespaces) > 0 {
+		promxyKubernetesSDConfig.Namespaces = &PromxyKubernetesSDNamespaces{Names: kubernetesSDConfig.Namespaces}
+	}
+	for _, selector := range kubernetesSDConfig.Selectors {
+		promxyKubernetesSDConfig.Selectors = append(promxyKubernetesSDConfig.Selectors, &PromxyKubernetesSDSelector{
+			Role:  selector.Role,
+			Label: selector.Label,
+			Field: selector.Field,
+		})
+	}
+	return promxyKubernetesSDConfig
+}
+
+// Returns the value of the key selected by `source`, or empty string if `source` is nil.
+func (r *PromxyServerGroupReconciler) getSecretOrConfigMapValue(
+	ctx context.Context,
+	namespace string,
+	source *kofv1beta1.SecretOrConfigMap,
+) (string, error) {
+	if source == nil {
+		return "", nil
+	}
+	if source.Secret != nil {
+		return r.getSecretValue(ctx, namespace, source.Secret)
+	}
+	if source.ConfigMap != nil {
+		configMap := &coreV1.ConfigMap{}
+		if err := r.Get(ctx, types.NamespacedName{
+			Name:      source.ConfigMap.Name,
+			Namespace: namespace,
+		}, configMap); err != nil {
+			return "", err
+		}
+		value, ok := configMap.Data[source.ConfigMap.Key]
+		if !ok {
+			return "", fmt.Errorf("key %q is not found in ConfigMap %s/%s", source.ConfigMap.Key, namespace, source.ConfigMap.Name)
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("either secret or config_map should be set")
+}
+
+// Returns the value of the key selected by `selector`, or empty string if `selector` is nil.
+func (r *PromxyServerGroupReconciler) getSecretValue(
+	ctx context.Context,
+	namespace string,
+	selector *coreV1.SecretKeySelector,
+) (string, error) {
+	if selector == nil {
+		return "", nil
+	}
+	secret := &coreV1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      selector.Name,
+		Namespace: namespace,
+	}, secret); err != nil {
+		return "", err
+	}
+	value, ok := secret.Data[selector.Key]
+	if !ok {
+		return "", fmt.Errorf("key %q is not found in Secret %s/%s", selector.Key, namespace, selector.Name)
+	}
+	return string(value), nil
+}
+
+// Applies `mutate` to each of `groups` and patches their status if it changed.
+// Errors are logged here, so callers reporting another error may ignore them.
+func (r *PromxyServerGroupReconciler) patchStatus(
+	ctx context.Context,
+	groups []*kofv1beta1.PromxyServerGroup,
+	mutate func(group *kofv1beta1.PromxyServerGroup),
+) error {
+	log := log.FromContext(ctx)
+
+	for _, group := range groups {
+		original := group.DeepCopy()
+		mutate(group)
+		if equality.Semantic.DeepEqual(original.Status, group.Status) {
+			continue
+		}
+		if err := r.Status().Patch(ctx, group, client.MergeFrom(original)); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			log.Error(err, "cannot update promxy server group status", "promxyServerGroup", group.Name)
+			return err
+		}
+	}
+	return nil
+}
+
+func setServerGroupCondition(
+	group *kofv1beta1.PromxyServerGroup,
+	conditionType string,
+	status metav1.ConditionStatus,
+	reason string,
+	message string,
+) {
+	meta.SetStatusCondition(&group.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: group.Generation,
+	})
+}
+
+// Adds the operator labels to the promxy config Secret, keeping the labels of Helm and others.
 func setSecretOperatorLabels(secret *coreV1.Secret) {
-	secret.Labels = map[string]string{utils.ManagedByLabel: utils.ManagedByValue}
+	if secret.Labels == nil {
+		secret.Labels = map[string]string{}
+	}
+	secret.Labels[utils.ManagedByLabel] = utils.ManagedByValue
+	secret.Labels[PromxyConfigSecretLabel] = "true"
+}
+
+// Sets the rendered config of the promxy config Secret with the time it was updated,
+// the time is kept if the config is unchanged, e.g. when only the labels are repaired.
+func setSecretConfigData(secret *coreV1.Secret, data string) {
+	if string(secret.Data["config.yaml"]) == data {
+		return
+	}
+	secret.StringData = map[string]string{
+		"config.yaml": data,
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[PromxyConfigUpdatedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+}
+
+// Returns the time the config of the promxy config Secret was updated,
+// or its creation time if it has no `PromxyConfigUpdatedAtAnnotation`.
+func getSecretConfigUpdatedAt(secret *coreV1.Secret) time.Time {
+	if updatedAt, err := time.Parse(time.RFC3339, secret.Annotations[PromxyConfigUpdatedAtAnnotation]); err == nil {
+		return updatedAt
+	}
+	return secret.CreationTimestamp.Time
+}
+
+func hasSecretOperatorLabels(secret *coreV1.Secret) bool {
+	return secret.Labels[utils.ManagedByLabel] == utils.ManagedByValue &&
+		secret.Labels[PromxyConfigSecretLabel] == "true"
+}
+
+// Returns names of Secrets referenced by `httpClient`.
+func getReferencedSecretNames(httpClient *kofv1beta1.HTTPClientConfig) []string {
+	names := []string{}
+	if httpClient.BasicAuth.CredentialsSecretName != "" {
+		names = append(names, httpClient.BasicAuth.CredentialsSecretName)
+	}
+	tlsConfig := &httpClient.TLSConfig
+	for _, source := range []*kofv1beta1.SecretOrConfigMap{tlsConfig.CA, tlsConfig.Cert} {
+		if source != nil && source.Secret != nil {
+			names = append(names, source.Secret.Name)
+		}
+	}
+	if tlsConfig.KeySecret != nil {
+		names = append(names, tlsConfig.KeySecret.Name)
+	}
+	if httpClient.BearerToken != nil && httpClient.BearerToken.Secret != nil {
+		names = append(names, httpClient.BearerToken.Secret.Name)
+	}
+	if httpClient.OAuth2 != nil {
+		names = append(names, httpClient.OAuth2.ClientSecret.Name)
+	}
+	return names
+}
+
+// Returns names of ConfigMaps referenced by `httpClient`.
+func getReferencedConfigMapNames(httpClient *kofv1beta1.HTTPClientConfig) []string {
+	names := []string{}
+	tlsConfig := &httpClient.TLSConfig
+	for _, source := range []*kofv1beta1.SecretOrConfigMap{tlsConfig.CA, tlsConfig.Cert} {
+		if source != nil && source.ConfigMap != nil {
+			names = append(names, source.ConfigMap.Name)
+		}
+	}
+	return names
+}
+
+// Returns names of objects referenced by remote write targets and alertmanagers of `promxyConfig`.
+func getPromxyConfigReferencedNames(
+	promxyConfig *kofv1beta1.PromxyConfig,
+	getReferencedNames func(httpClient *kofv1beta1.HTTPClientConfig) []string,
+) []string {
+	names := []string{}
+	for _, remoteWrite := range promxyConfig.Spec.RemoteWrite {
+		names = append(names, getReferencedNames(getHTTPClientConfig(&remoteWrite.HTTPAuthConfig))...)
+	}
+	for _, alertmanager := range promxyConfig.Spec.Alertmanagers {
+		names = append(names, getReferencedNames(getHTTPClientConfig(&alertmanager.HTTPAuthConfig))...)
+	}
+	return names
+}
+
+// Returns a map function enqueuing the groups and promxy configs that reference an object
+// with one of the names returned by `getReferencedNames`.
+func (r *PromxyServerGroupReconciler) mapReferencedObjectToServerGroups(
+	getReferencedNames func(httpClient *kofv1beta1.HTTPClientConfig) []string,
+) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		log := log.FromContext(ctx)
+
+		promxyServerGroupsList := &kofv1beta1.PromxyServerGroupList{}
+		if err := r.List(ctx, promxyServerGroupsList, client.InNamespace(obj.GetNamespace())); err != nil {
+			log.Error(err, "cannot get promxy server group list")
+			return nil
+		}
+
+		requests := []reconcile.Request{}
+		for _, promxyServerGroup := range promxyServerGroupsList.Items {
+			if slices.Contains(getReferencedNames(&promxyServerGroup.Spec.HttpClient), obj.GetName()) {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Name:      promxyServerGroup.Name,
+						Namespace: promxyServerGroup.Namespace,
+					},
+				})
+			}
+		}
+
+		promxyConfigsList := &kofv1beta1.PromxyConfigList{}
+		if err := r.List(ctx, promxyConfigsList, client.InNamespace(obj.GetNamespace())); err != nil {
+			log.Error(err, "cannot get promxy config list")
+			return nil
+		}
+		for _, promxyConfig := range promxyConfigsList.Items {
+			if slices.Contains(getPromxyConfigReferencedNames(&promxyConfig, getReferencedNames), obj.GetName()) {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Name:      promxyConfig.Name,
+						Namespace: promxyConfig.Namespace,
+					},
+				})
+			}
+		}
+		return requests
+	}
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *PromxyServerGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&kofv1beta1.PromxyServerGroup{}).
+		// Status updates should not trigger reconciliation, label updates should.
+		For(&kofv1beta1.PromxyServerGroup{}, builder.WithPredicates(predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			predicate.LabelChangedPredicate{},
+		))).
+		// Global settings are merged into the promxy config Secret with the same name.
+		Watches(
+			&kofv1beta1.PromxyConfig{},
+			&handler.EnqueueRequestForObject{},
+		).
+		// Rotated credentials and TLS materials should be re-rendered.
+		Watches(
+			&coreV1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.mapReferencedObjectToServerGroups(getReferencedSecretNames)),
+		).
+		Watches(
+			&coreV1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.mapReferencedObjectToServerGroups(getReferencedConfigMapNames)),
+		).
 		Complete(r)
 }
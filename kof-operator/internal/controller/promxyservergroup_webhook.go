@@ -0,0 +1,158 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	kofv1beta1 "github.com/k0rdent/kof/kof-operator/api/v1beta1"
+)
+
+// DefaultPromxySecretName is the promxy config Secret of the kof-mothership chart.
+const DefaultPromxySecretName = "kof-mothership-promxy-config"
+
+var promxyServerGroupSchemes = []string{"http", "https"}
+
+// PromxyServerGroupWebhook defaults and validates PromxyServerGroup objects at admission.
+type PromxyServerGroupWebhook struct{}
+
+// +kubebuilder:webhook:path=/mutate-kof-k0rdent-mirantis-com-v1beta1-promxyservergroup,mutating=true,failurePolicy=fail,sideEffects=None,groups=kof.k0rdent.mirantis.com,resources=promxyservergroups,verbs=create;update,versions=v1beta1,name=mpromxyservergroup-v1beta1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-kof-k0rdent-mirantis-com-v1beta1-promxyservergroup,mutating=false,failurePolicy=fail,sideEffects=None,groups=kof.k0rdent.mirantis.com,resources=promxyservergroups,verbs=create;update,versions=v1beta1,name=vpromxyservergroup-v1beta1.kb.io,admissionReviewVersions=v1
+
+// SetupWebhookWithManager registers the webhooks in the manager.
+func (w *PromxyServerGroupWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&kofv1beta1.PromxyServerGroup{}).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
+
+// Default sets the scheme, the dial timeout and the promxy secret name label if they are not set.
+func (w *PromxyServerGroupWebhook) Default(ctx context.Context, obj runtime.Object) error {
+	group, ok := obj.(*kofv1beta1.PromxyServerGroup)
+	if !ok {
+		return fmt.Errorf("expected a PromxyServerGroup but got a %T", obj)
+	}
+
+	if group.Spec.Scheme == "" {
+		group.Spec.Scheme = "http"
+	}
+	if group.Spec.HttpClient.DialTimeout.Duration == 0 {
+		group.Spec.HttpClient.DialTimeout = defaultDialTimeout
+	}
+	if _, ok := group.Labels[PromxySecretNameLabel]; !ok {
+		if group.Labels == nil {
+			group.Labels = make(map[string]string)
+		}
+		group.Labels[PromxySecretNameLabel] = GetServedPromxySecretName()
+	}
+	return nil
+}
+
+// ValidateCreate implements admission.CustomValidator.
+func (w *PromxyServerGroupWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, validatePromxyServerGroupObject(nil, obj)
+}
+
+// ValidateUpdate implements admission.CustomValidator.
+func (w *PromxyServerGroupWebhook) ValidateUpdate(
+	ctx context.Context,
+	oldObj runtime.Object,
+	newObj runtime.Object,
+) (admission.Warnings, error) {
+	return nil, validatePromxyServerGroupObject(oldObj, newObj)
+}
+
+// ValidateDelete implements admission.CustomValidator, deletion is always allowed.
+func (w *PromxyServerGroupWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// Validates the new `obj`, `oldObj` is nil on create.
+func validatePromxyServerGroupObject(oldObj runtime.Object, obj runtime.Object) error {
+	group, ok := obj.(*kofv1beta1.PromxyServerGroup)
+	if !ok {
+		return fmt.Errorf("expected a PromxyServerGroup but got a %T", obj)
+	}
+	oldGroup, _ := oldObj.(*kofv1beta1.PromxyServerGroup)
+
+	var errs field.ErrorList
+	labelPath := field.NewPath("metadata", "labels").Key(PromxySecretNameLabel)
+	if name, ok := group.Labels[PromxySecretNameLabel]; !ok {
+		errs = append(errs, field.Required(labelPath, "promxy config Secret name is required"))
+	} else {
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			errs = append(errs, field.Invalid(labelPath, name, msg))
+		}
+		// Groups labeled before are kept updatable, the controller excludes them from the config.
+		servedName := GetServedPromxySecretName()
+		if name != servedName && (oldGroup == nil || oldGroup.Labels[PromxySecretNameLabel] != name) {
+			errs = append(errs, field.NotSupported(labelPath, name, []string{servedName}))
+		}
+	}
+
+	specPath := field.NewPath("spec")
+	spec := &group.Spec
+	if spec.Scheme != "http" && spec.Scheme != "https" {
+		errs = append(errs, field.NotSupported(specPath.Child("scheme"), spec.Scheme, promxyServerGroupSchemes))
+	}
+	if len(spec.Targets) == 0 && len(spec.DNSSDConfigs) == 0 && len(spec.KubernetesSDConfigs) == 0 {
+		errs = append(errs, field.Required(
+			specPath.Child("targets"),
+			"at least one target is required if dns_sd_configs and kubernetes_sd_configs are not set",
+		))
+	}
+	for i, target := range spec.Targets {
+		if err := validateTarget(target); err != nil {
+			errs = append(errs, field.Invalid(specPath.Child("targets").Index(i), target, err.Error()))
+		}
+	}
+	if err := validateHTTPClientConfig(&spec.HttpClient); err != nil {
+		errs = append(errs, field.Forbidden(specPath.Child("http_client"), err.Error()))
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(kofv1beta1.GroupVersion.WithKind("PromxyServerGroup").GroupKind(), group.Name, errs)
+}
+
+// Checks `target` is `host:port` with a non-empty host and a valid port number.
+func validateTarget(target string) error {
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		return fmt.Errorf("host is empty")
+	}
+	if portNumber, err := strconv.ParseUint(port, 10, 16); err != nil || portNumber == 0 {
+		return fmt.Errorf("port %q is not a number in 1-65535", port)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kofv1beta1 "github.com/k0rdent/kof/kof-operator/api/v1beta1"
+)
+
+var _ = Describe("PromxyServerGroup Webhook", func() {
+	ctx := context.Background()
+	const promxyServerGroupName = "test-webhook"
+
+	var resource *kofv1beta1.PromxyServerGroup
+
+	BeforeEach(func() {
+		resource = &kofv1beta1.PromxyServerGroup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      promxyServerGroupName,
+				Namespace: "default",
+				Labels:    map[string]string{PromxySecretNameLabel: DefaultPromxySecretName},
+			},
+			Spec: kofv1beta1.PromxyServerGroupSpec{
+				ClusterName: "test-cluster",
+				Targets:     []string{"test.example.net:443"},
+				Scheme:      "https",
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, resource))).To(Succeed())
+	})
+
+	It("should default the scheme, dial timeout and secret name label", func() {
+		GinkgoT().Setenv("PROMXY_SECRET_NAME", "served-promxy-config")
+		resource.Labels = nil
+		resource.Spec.Scheme = ""
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+		Expect(resource.Spec.Scheme).To(Equal("http"))
+		Expect(resource.Spec.HttpClient.DialTimeout).To(Equal(defaultDialTimeout))
+		Expect(resource.Labels).To(HaveKeyWithValue(PromxySecretNameLabel, "served-promxy-config"))
+	})
+
+	It("should keep the values that are set", func() {
+		resource.Spec.HttpClient.DialTimeout = metav1.Duration{Duration: defaultDialTimeout.Duration * 2}
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+		Expect(resource.Spec.Scheme).To(Equal("https"))
+		Expect(resource.Spec.HttpClient.DialTimeout.Duration).To(Equal(defaultDialTimeout.Duration * 2))
+		Expect(resource.Labels).To(HaveKeyWithValue(PromxySecretNameLabel, DefaultPromxySecretName))
+	})
+
+	It("should accept service discovery without static targets", func() {
+		resource.Spec.Targets = nil
+		resource.Spec.DNSSDConfigs = []kofv1beta1.DNSSDConfig{{Names: []string{"_http._tcp.vmselect.example.net"}}}
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+	})
+
+	DescribeTable("should reject invalid resources",
+		func(mutate func(*kofv1beta1.PromxyServerGroup), message string) {
+			mutate(resource)
+			err := k8sClient.Create(ctx, resource)
+			Expect(errors.IsInvalid(err)).To(BeTrue(), "unexpected error: %v", err)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("unsupported scheme", func(group *kofv1beta1.PromxyServerGroup) {
+			group.Spec.Scheme = "ftp"
+		}, `spec.scheme: Unsupported value: "ftp"`),
+		Entry("no targets", func(group *kofv1beta1.PromxyServerGroup) {
+			group.Spec.Targets = nil
+		}, "spec.targets: Required value"),
+		Entry("target without port", func(group *kofv1beta1.PromxyServerGroup) {
+			group.Spec.Targets = []string{"test.example.net:443", "test.example.net"}
+		}, "spec.targets[1]: Invalid value"),
+		Entry("target without host", func(group *kofv1beta1.PromxyServerGroup) {
+			group.Spec.Targets = []string{":443"}
+		}, "host is empty"),
+		Entry("target with invalid port", func(group *kofv1beta1.PromxyServerGroup) {
+			group.Spec.Targets = []string{"test.example.net:https"}
+		}, `port "https" is not a number`),
+		Entry("several auth methods", func(group *kofv1beta1.PromxyServerGroup) {
+			group.Spec.HttpClient.BasicAuth.CredentialsSecretName = "creds"
+			group.Spec.HttpClient.BearerToken = &kofv1beta1.BearerToken{File: "/var/run/token"}
+		}, "at most one of basic_auth, bearer_token and oauth2"),
+		Entry("mTLS cert without key", func(group *kofv1beta1.PromxyServerGroup) {
+			group.Spec.HttpClient.TLSConfig.Cert = &kofv1beta1.SecretOrConfigMap{
+				Secret: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "tls"},
+					Key:                  "tls.crt",
+				},
+			}
+		}, "both tls_config.cert and tls_config.key_secret"),
+		Entry("empty secret name label", func(group *kofv1beta1.PromxyServerGroup) {
+			group.Labels[PromxySecretNameLabel] = ""
+		}, "metadata.labels["+PromxySecretNameLabel+"]: Invalid value"),
+		Entry("secret not served by promxy", func(group *kofv1beta1.PromxyServerGroup) {
+			group.Labels[PromxySecretNameLabel] = "other-promxy-config"
+		}, `metadata.labels[`+PromxySecretNameLabel+`]: Unsupported value: "other-promxy-config"`),
+	)
+
+	It("should reject invalid updates", func() {
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+		resource.Spec.Targets = []string{"test.example.net:0"}
+		err := k8sClient.Update(ctx, resource)
+		Expect(errors.IsInvalid(err)).To(BeTrue(), "unexpected error: %v", err)
+	})
+
+	It("should reject relabeling to a secret not served by promxy but keep updating labeled groups", func() {
+		Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+		By("Serving another secret after the group was labeled")
+		GinkgoT().Setenv("PROMXY_SECRET_NAME", "served-promxy-config")
+		resource.Spec.Targets = []string{"test.example.net:8443"}
+		Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+		resource.Labels[PromxySecretNameLabel] = "other-promxy-config"
+		err := k8sClient.Update(ctx, resource)
+		Expect(errors.IsInvalid(err)).To(BeTrue(), "unexpected error: %v", err)
+	})
+})
@@ -0,0 +1,208 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+
+	kcmv1beta1 "github.com/K0rdent/kcm/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+// Optional ConfigMap in the release namespace, mapping child clusters to regional clusters.
+const KofRegionalClusterMappingConfigMapName = "kof-regional-cluster-mapping"
+const RegionalClusterMappingKey = "mapping"
+
+// RegionalClusterMapping declares the regional cluster serving the matching child clusters,
+// e.g. adopted and remote clusters which have no location known to `cloudLocations`.
+// All the set fields must match, and at least one of them must be set.
+type RegionalClusterMapping struct {
+	// Name of the regional ClusterDeployment in the namespace of the child ClusterDeployment.
+	RegionalClusterName string `json:"regionalClusterName"`
+
+	// Cloud of the child, detected from the prefix of its template, e.g. "aws" or "adopted".
+	Cloud string `json:"cloud,omitempty"`
+
+	// Location of the child as returned by `cloudLocations`, e.g. AWS region or vSphere datacenter.
+	Location string `json:"location,omitempty"`
+
+	// Names of the child ClusterDeployments.
+	ClusterNames []string `json:"clusterNames,omitempty"`
+
+	// Selector of the child ClusterDeployments by labels.
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+}
+
+// Returns the mappings from the `KofRegionalClusterMappingConfigMapName` ConfigMap,
+// or nil if it is not found.
+func readRegionalClusterMappings(ctx context.Context, reader client.Reader) ([]RegionalClusterMapping, error) {
+	log := log.FromContext(ctx)
+
+	releaseNamespace, ok := os.LookupEnv("RELEASE_NAMESPACE")
+	if !ok {
+		return nil, fmt.Errorf("required RELEASE_NAMESPACE env var is not set")
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := reader.Get(ctx, types.NamespacedName{
+		Name:      KofRegionalClusterMappingConfigMapName,
+		Namespace: releaseNamespace,
+	}, configMap); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		log.Error(
+			err, "cannot read regional cluster mapping ConfigMap",
+			"configMapName", KofRegionalClusterMappingConfigMapName,
+		)
+		return nil, err
+	}
+
+	mappings, err := parseRegionalClusterMappings(configMap.Data[RegionalClusterMappingKey])
+	if err != nil {
+		log.Error(
+			err, "invalid regional cluster mapping",
+			"configMapName", KofRegionalClusterMappingConfigMapName,
+			"key", RegionalClusterMappingKey,
+		)
+		return nil, err
+	}
+	return mappings, nil
+}
+
+func parseRegionalClusterMappings(data string) ([]RegionalClusterMapping, error) {
+	var mappings []RegionalClusterMapping
+	if err := yaml.UnmarshalStrict([]byte(data), &mappings); err != nil {
+		return nil, err
+	}
+
+	for i, mapping := range mappings {
+		if mapping.RegionalClusterName == "" {
+			return nil, fmt.Errorf("mapping %d: regionalClusterName is required", i)
+		}
+		if mapping.Cloud == "" && mapping.Location == "" &&
+			len(mapping.ClusterNames) == 0 && mapping.ClusterSelector == nil {
+			return nil, fmt.Errorf(
+				"mapping %d: at least one of cloud, location, clusterNames or clusterSelector is required", i,
+			)
+		}
+		if mapping.ClusterSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(mapping.ClusterSelector); err != nil {
+				return nil, fmt.Errorf("mapping %d: invalid clusterSelector: %w", i, err)
+			}
+		}
+	}
+	return mappings, nil
+}
+
+// Returns true if all the set fields of the mapping match the child cluster.
+func (m *RegionalClusterMapping) matches(
+	childClusterDeployment *kcmv1beta1.ClusterDeployment,
+	childCloud string,
+	childLocation string,
+) bool {
+	if m.Cloud != "" && m.Cloud != childCloud {
+		return false
+	}
+	if m.Location != "" && m.Location != childLocation {
+		return false
+	}
+	if len(m.ClusterNames) > 0 && !slices.Contains(m.ClusterNames, childClusterDeployment.Name) {
+		return false
+	}
+	if m.ClusterSelector != nil {
+		// The selector is validated by `parseRegionalClusterMappings`.
+		selector, err := metav1.LabelSelectorAsSelector(m.ClusterSelector)
+		if err != nil || !selector.Matches(labels.Set(childClusterDeployment.Labels)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the regional ClusterDeployments mapped to the child cluster, sorted by name.
+// The second result is false if no mapping matches the child, so it should be discovered by location.
+// Mapped regional clusters which are not found or have no regional role are skipped.
+func findMappedRegionalClusterDeployments(
+	ctx context.Context,
+	reader client.Reader,
+	childClusterDeployment *kcmv1beta1.ClusterDeployment,
+	childCloud string,
+	childLocation string,
+) ([]kcmv1beta1.ClusterDeployment, bool, error) {
+	log := log.FromContext(ctx)
+
+	mappings, err := readRegionalClusterMappings(ctx, reader)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var regionalClusterNames []string
+	for _, mapping := range mappings {
+		if mapping.matches(childClusterDeployment, childCloud, childLocation) &&
+			!slices.Contains(regionalClusterNames, mapping.RegionalClusterName) {
+			regionalClusterNames = append(regionalClusterNames, mapping.RegionalClusterName)
+		}
+	}
+	if len(regionalClusterNames) == 0 {
+		return nil, false, nil
+	}
+	slices.Sort(regionalClusterNames)
+
+	var found []kcmv1beta1.ClusterDeployment
+	for _, regionalClusterName := range regionalClusterNames {
+		regionalClusterDeployment := kcmv1beta1.ClusterDeployment{}
+		if err := reader.Get(ctx, types.NamespacedName{
+			Name:      regionalClusterName,
+			Namespace: childClusterDeployment.Namespace,
+		}, &regionalClusterDeployment); err != nil {
+			if errors.IsNotFound(err) {
+				log.Info(
+					"mapped regional ClusterDeployment is not found",
+					"childClusterDeploymentName", childClusterDeployment.Name,
+					"regionalClusterDeploymentName", regionalClusterName,
+				)
+				continue
+			}
+			log.Error(
+				err, "cannot read mapped regional ClusterDeployment",
+				"regionalClusterDeploymentName", regionalClusterName,
+			)
+			return nil, true, err
+		}
+		if regionalClusterDeployment.Labels[KofClusterRoleLabel] != "regional" {
+			log.Info(
+				"mapped ClusterDeployment is not regional",
+				"childClusterDeploymentName", childClusterDeployment.Name,
+				"regionalClusterDeploymentName", regionalClusterName,
+			)
+			continue
+		}
+		found = append(found, regionalClusterDeployment)
+	}
+	return found, true, nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	kcmv1beta1 "github.com/K0rdent/kcm/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Regional cluster mapping", func() {
+	DescribeTable("should match location of clouds",
+		func(cloud string, childConfig string, regionalConfig string, expected bool) {
+			childClusterDeploymentConfig, err := ReadClusterDeploymentConfig([]byte(childConfig))
+			Expect(err).NotTo(HaveOccurred())
+			regionalClusterDeploymentConfig, err := ReadClusterDeploymentConfig([]byte(regionalConfig))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(locationIsTheSame(cloud, childClusterDeploymentConfig, regionalClusterDeploymentConfig)).
+				To(Equal(expected))
+		},
+		Entry("aws", "aws", `{"region": "us-east-2"}`, `{"region": "us-east-2"}`, true),
+		Entry("aws other region", "aws", `{"region": "us-east-1"}`, `{"region": "us-east-2"}`, false),
+		Entry("azure", "azure", `{"location": "westus"}`, `{"location": "westus"}`, true),
+		Entry("gcp", "gcp", `{"project": "a", "region": "us-east1"}`, `{"project": "b", "region": "us-east1"}`, true),
+		Entry("openstack", "openstack", `{"identityRef": {"region": "RegionOne"}}`,
+			`{"identityRef": {"region": "RegionTwo"}}`, false),
+		Entry("vsphere", "vsphere", `{"vsphere": {"datacenter": "dc1"}}`, `{"vsphere": {"datacenter": "dc1"}}`, true),
+		Entry("docker", "docker", `{}`, `{}`, true),
+		Entry("adopted", "adopted", `{}`, `{}`, false),
+		Entry("remote", "remote", `{}`, `{}`, false),
+	)
+
+	It("should match all the set fields of the mapping", func() {
+		child := &kcmv1beta1.ClusterDeployment{ObjectMeta: metav1.ObjectMeta{
+			Name:   "edge-1",
+			Labels: map[string]string{"example.com/site": "edge"},
+		}}
+
+		mapping := RegionalClusterMapping{RegionalClusterName: "regional", Cloud: "remote"}
+		Expect(mapping.matches(child, "remote", "")).To(BeTrue())
+		Expect(mapping.matches(child, "adopted", "")).To(BeFalse())
+
+		mapping.ClusterNames = []string{"edge-1", "edge-2"}
+		Expect(mapping.matches(child, "remote", "")).To(BeTrue())
+
+		mapping.ClusterSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"example.com/site": "core"}}
+		Expect(mapping.matches(child, "remote", "")).To(BeFalse())
+
+		mapping = RegionalClusterMapping{RegionalClusterName: "regional", Cloud: "gcp", Location: "us-east1"}
+		Expect(mapping.matches(child, "gcp", "us-east1")).To(BeTrue())
+		Expect(mapping.matches(child, "gcp", "us-west1")).To(BeFalse())
+	})
+
+	DescribeTable("should reject invalid mappings",
+		func(data string, message string) {
+			_, err := parseRegionalClusterMappings(data)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("no regional cluster name", `[{"cloud": "adopted"}]`, "regionalClusterName is required"),
+		Entry("no matching fields", `[{"regionalClusterName": "regional"}]`, "at least one of"),
+		Entry("unknown field", `[{"regionalClusterName": "regional", "clusters": ["edge-1"]}]`, "unknown field"),
+		Entry("invalid selector", `[{"regionalClusterName": "regional", "clusterSelector": {
+			"matchExpressions": [{"key": "site", "operator": "Like"}]
+		}}]`, "invalid clusterSelector"),
+	)
+})
@@ -2,33 +2,374 @@ package controller
 
 import (
 	"bytes"
-	_ "embed"
-	"text/template"
-)
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
 
-//go:embed template/secret.tmpl
-var promxySecretTemplate string
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	promconfig "github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/discovery/dns"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/prometheus/prometheus/model/relabel"
+	yamlv2 "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
 
+// PromxyConfig is the promxy config file:
+// the Prometheus config extended with the `promxy` section.
 type PromxyConfig struct {
-	RemoteWriteUrl string
-	ServerGroups   []*PromxyConfigServerGroup
+	PrometheusConfig `yaml:",inline"`
+	Promxy           PromxyServerGroupsConfig `yaml:"promxy"`
+}
+
+// PrometheusConfig is the subset of the Prometheus config used by promxy.
+type PrometheusConfig struct {
+	Global      PromxyGlobalConfig         `yaml:"global"`
+	RuleFiles   []string                   `yaml:"rule_files"`
+	Alerting    PromxyAlertingConfig       `yaml:"alerting"`
+	RemoteWrite []*PromxyRemoteWriteConfig `yaml:"remote_write"`
+}
+
+type PromxyGlobalConfig struct {
+	EvaluationInterval string            `yaml:"evaluation_interval"`
+	ExternalLabels     map[string]string `yaml:"external_labels,omitempty"`
+}
+
+type PromxyAlertingConfig struct {
+	AlertRelabelConfigs []*PromxyRelabelConfig      `yaml:"alert_relabel_configs,omitempty"`
+	Alertmanagers       []*PromxyAlertmanagerConfig `yaml:"alertmanagers"`
+}
+
+type PromxyAlertmanagerConfig struct {
+	Scheme                 string                `yaml:"scheme"`
+	PathPrefix             string                `yaml:"path_prefix,omitempty"`
+	StaticConfigs          []*PromxyStaticConfig `yaml:"static_configs"`
+	PromxyHTTPClientConfig `yaml:",inline"`
+}
+
+type PromxyStaticConfig struct {
+	Targets []string `yaml:"targets"`
+}
+
+type PromxyRemoteWriteConfig struct {
+	URL                    string `yaml:"url"`
+	PromxyHTTPClientConfig `yaml:",inline"`
+}
+
+type PromxyServerGroupsConfig struct {
+	ServerGroups []*PromxyServerGroupConfig `yaml:"server_groups"`
+}
+
+type PromxyServerGroupConfig struct {
+	StaticConfigs       []*PromxyStaticConfig       `yaml:"static_configs,omitempty"`
+	DNSSDConfigs        []*PromxyDNSSDConfig        `yaml:"dns_sd_configs,omitempty"`
+	KubernetesSDConfigs []*PromxyKubernetesSDConfig `yaml:"kubernetes_sd_configs,omitempty"`
+	PathPrefix          string                      `yaml:"path_prefix,omitempty"`
+	Scheme              string                      `yaml:"scheme,omitempty"`
+	HTTPClient          PromxyHTTPClientConfig      `yaml:"http_client"`
+	Labels              map[string]string           `yaml:"labels,omitempty"`
+	RelabelConfigs      []*PromxyRelabelConfig      `yaml:"relabel_configs,omitempty"`
+	QueryParams         map[string]string           `yaml:"query_params,omitempty"`
+	RemoteRead          bool                        `yaml:"remote_read,omitempty"`
+	RemoteReadPath      string                      `yaml:"remote_read_path,omitempty"`
+	AntiAffinity        string                      `yaml:"anti_affinity,omitempty"`
+	Timeout             string                      `yaml:"timeout,omitempty"`
+	IgnoreError         bool                        `yaml:"ignore_error"`
+}
+
+type PromxyRelabelConfig struct {
+	SourceLabels []string `yaml:"source_labels,flow,omitempty"`
+	Separator    string   `yaml:"separator,omitempty"`
+	Regex        string   `yaml:"regex,omitempty"`
+	Modulus      uint64   `yaml:"modulus,omitempty"`
+	TargetLabel  string   `yaml:"target_label,omitempty"`
+	Replacement  string   `yaml:"replacement,omitempty"`
+	Action       string   `yaml:"action,omitempty"`
+}
+
+type PromxyDNSSDConfig struct {
+	Names           []string `yaml:"names"`
+	Type            string   `yaml:"type,omitempty"`
+	Port            int32    `yaml:"port,omitempty"`
+	RefreshInterval string   `yaml:"refresh_interval,omitempty"`
+}
+
+type PromxyKubernetesSDConfig struct {
+	Role           string                        `yaml:"role"`
+	APIServer      string                        `yaml:"api_server,omitempty"`
+	KubeconfigFile string                        `yaml:"kubeconfig_file,omitempty"`
+	Namespaces     *PromxyKubernetesSDNamespaces `yaml:"namespaces,omitempty"`
+	Selectors      []*PromxyKubernetesSDSelector `yaml:"selectors,omitempty"`
+}
+
+type PromxyKubernetesSDNamespaces struct {
+	Names []string `yaml:"names"`
+}
+
+type PromxyKubernetesSDSelector struct {
+	Role  string `yaml:"role"`
+	Label string `yaml:"label,omitempty"`
+	Field string `yaml:"field,omitempty"`
+}
+
+type PromxyHTTPClientConfig struct {
+	DialTimeout   string               `yaml:"dial_timeout,omitempty"`
+	TLSConfig     *PromxyTLSConfig     `yaml:"tls_config,omitempty"`
+	BasicAuth     *PromxyBasicAuth     `yaml:"basic_auth,omitempty"`
+	Authorization *PromxyAuthorization `yaml:"authorization,omitempty"`
+	OAuth2        *PromxyOAuth2        `yaml:"oauth2,omitempty"`
+}
+
+type PromxyTLSConfig struct {
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	ServerName         string `yaml:"server_name,omitempty"`
+	CA                 string `yaml:"ca,omitempty"`
+	Cert               string `yaml:"cert,omitempty"`
+	Key                string `yaml:"key,omitempty"`
+}
+
+type PromxyBasicAuth struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type PromxyAuthorization struct {
+	Type            string `yaml:"type"`
+	Credentials     string `yaml:"credentials,omitempty"`
+	CredentialsFile string `yaml:"credentials_file,omitempty"`
+}
+
+type PromxyOAuth2 struct {
+	ClientID       string            `yaml:"client_id"`
+	ClientSecret   string            `yaml:"client_secret"`
+	TokenURL       string            `yaml:"token_url"`
+	Scopes         []string          `yaml:"scopes,omitempty"`
+	EndpointParams map[string]string `yaml:"endpoint_params,omitempty"`
+}
+
+// NewPromxyConfig returns the promxy config with defaults of the kof-mothership chart.
+func NewPromxyConfig(remoteWriteUrl string) *PromxyConfig {
+	return &PromxyConfig{
+		PrometheusConfig: PrometheusConfig{
+			Global: PromxyGlobalConfig{
+				EvaluationInterval: "5s",
+				ExternalLabels:     map[string]string{"source": "promxy"},
+			},
+			RuleFiles: []string{"/etc/promxy/rules/*.yaml"},
+			Alerting: PromxyAlertingConfig{
+				Alertmanagers: []*PromxyAlertmanagerConfig{{
+					Scheme: "http",
+					StaticConfigs: []*PromxyStaticConfig{{
+						Targets: []string{"vmalertmanager-cluster:9093"},
+					}},
+				}},
+			},
+			RemoteWrite: []*PromxyRemoteWriteConfig{{URL: remoteWriteUrl}},
+		},
+		Promxy: PromxyServerGroupsConfig{
+			ServerGroups: []*PromxyServerGroupConfig{},
+		},
+	}
+}
+
+// RenderPromxyConfig returns the promxy config and its hash reported in the status.
+// The config carries the hash in a no-op alert relabeling rule,
+// so a promxy replica serving its config confirms which config it loaded.
+func RenderPromxyConfig(config *PromxyConfig) (data string, configHash string, err error) {
+	if data, err = marshalYAML(config); err != nil {
+		return "", "", err
+	}
+	configHash = PromxyConfigHash(data)
+
+	marked := *config
+	marked.Alerting.AlertRelabelConfigs = append(
+		slices.Clone(config.Alerting.AlertRelabelConfigs),
+		&PromxyRelabelConfig{Regex: promxyConfigHashMarker(configHash), Action: "labeldrop"},
+	)
+	if data, err = marshalYAML(&marked); err != nil {
+		return "", "", err
+	}
+	return data, configHash, nil
+}
+
+// Returns the label name dropped by the alert relabeling rule carrying `configHash`,
+// alerts never have this label.
+func promxyConfigHashMarker(configHash string) string {
+	return "kof_promxy_config_hash_" + configHash
+}
+
+// PromxyConfigHash returns the hash of the promxy config rendered without its hash marker.
+func PromxyConfigHash(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// Mirrors `servergroup.Config` of promxy using the upstream Prometheus types it is built on.
+// promxy cannot be a module dependency, so the parity of the keys is pinned by `promxyServerGroupConfigKeys` in tests.
+type promxyServerGroupValidation struct {
+	StaticConfigs       []*targetgroup.Group            `yaml:"static_configs"`
+	DNSSDConfigs        []*dns.SDConfig                 `yaml:"dns_sd_configs"`
+	KubernetesSDConfigs []*promxyKubernetesSDValidation `yaml:"kubernetes_sd_configs"`
+	PathPrefix          string                          `yaml:"path_prefix"`
+	Scheme              string                          `yaml:"scheme"`
+	HTTPClient          promxyHTTPClientValidation      `yaml:"http_client"`
+	Labels              model.LabelSet                  `yaml:"labels"`
+	RelabelConfigs      []*relabel.Config               `yaml:"relabel_configs"`
+	QueryParams         map[string]string               `yaml:"query_params"`
+	RemoteRead          bool                            `yaml:"remote_read"`
+	RemoteReadPath      string                          `yaml:"remote_read_path"`
+	AntiAffinity        time.Duration                   `yaml:"anti_affinity"`
+	Timeout             time.Duration                   `yaml:"timeout"`
+	IgnoreError         bool                            `yaml:"ignore_error"`
+}
+
+// Mirrors `kubernetes.SDConfig` of Prometheus, which is not imported
+// as it is built against another version of client-go.
+type promxyKubernetesSDValidation struct {
+	APIServer  config_util.URL `yaml:"api_server"`
+	Role       string          `yaml:"role"`
+	KubeConfig string          `yaml:"kubeconfig_file"`
+	Namespaces struct {
+		Names []string `yaml:"names"`
+	} `yaml:"namespaces"`
+	Selectors []struct {
+		Role  string `yaml:"role"`
+		Label string `yaml:"label"`
+		Field string `yaml:"field"`
+	} `yaml:"selectors"`
+}
+
+// Roles of the kubernetes service discovery with the roles of their allowed selectors.
+var kubernetesSDSelectorRoles = map[string][]string{
+	"endpoints":     {"endpoints", "pod", "service"},
+	"endpointslice": {"endpointslice", "pod", "service"},
+	"pod":           {"pod"},
+	"service":       {"service"},
+}
+
+func (c *promxyKubernetesSDValidation) validate() error {
+	selectorRoles, ok := kubernetesSDSelectorRoles[c.Role]
+	if !ok {
+		return fmt.Errorf("invalid role %q, expected one of endpoints, endpointslice, pod or service", c.Role)
+	}
+	if c.APIServer.URL != nil && c.KubeConfig != "" {
+		return fmt.Errorf("cannot use kubeconfig_file and api_server simultaneously")
+	}
+	foundSelectorRoles := map[string]bool{}
+	for _, selector := range c.Selectors {
+		if !slices.Contains(selectorRoles, selector.Role) {
+			return fmt.Errorf("%s role supports only %v selectors", c.Role, selectorRoles)
+		}
+		if foundSelectorRoles[selector.Role] {
+			return fmt.Errorf("duplicated selector role %s", selector.Role)
+		}
+		foundSelectorRoles[selector.Role] = true
+		if _, err := labels.Parse(selector.Label); err != nil {
+			return err
+		}
+		if _, err := fields.ParseSelector(selector.Field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type promxyHTTPClientValidation struct {
+	DialTimeout time.Duration                `yaml:"dial_timeout"`
+	HTTPConfig  config_util.HTTPClientConfig `yaml:",inline"`
+}
+
+// ValidatePromxyServerGroup loads `serverGroup` back the way promxy does and validates it.
+func ValidatePromxyServerGroup(serverGroup *PromxyServerGroupConfig) error {
+	data, err := marshalYAML(serverGroup)
+	if err != nil {
+		return err
+	}
+	return validatePromxyServerGroupData([]byte(data))
+}
+
+func validatePromxyServerGroupData(data []byte) error {
+	serverGroup := &promxyServerGroupValidation{}
+	if err := yamlv2.UnmarshalStrict(data, serverGroup); err != nil {
+		return err
+	}
+	if serverGroup.Scheme != "" && serverGroup.Scheme != "http" && serverGroup.Scheme != "https" {
+		return fmt.Errorf("invalid scheme %q, expected http or https", serverGroup.Scheme)
+	}
+	if err := serverGroup.Labels.Validate(); err != nil {
+		return err
+	}
+	for i, kubernetesSDConfig := range serverGroup.KubernetesSDConfigs {
+		if err := kubernetesSDConfig.validate(); err != nil {
+			return fmt.Errorf("kubernetes_sd_configs[%d]: %w", i, err)
+		}
+	}
+	// `UnmarshalYAML` of the inlined config is not called, so validate it explicitly.
+	return serverGroup.HTTPClient.HTTPConfig.Validate()
+}
+
+// ValidatePromxyConfig loads the rendered promxy config back the way promxy does:
+// the `promxy` section via server group types and the rest via Prometheus config.
+func ValidatePromxyConfig(data string) error {
+	sections := yamlv2.MapSlice{}
+	if err := yamlv2.UnmarshalStrict([]byte(data), &sections); err != nil {
+		return err
+	}
+
+	prometheusSections := yamlv2.MapSlice{}
+	for _, section := range sections {
+		if section.Key != "promxy" {
+			prometheusSections = append(prometheusSections, section)
+			continue
+		}
+		promxySection := struct {
+			ServerGroups []yamlv2.MapSlice `yaml:"server_groups"`
+		}{}
+		if err := remarshalYAMLv2(section.Value, &promxySection); err != nil {
+			return fmt.Errorf("promxy: %w", err)
+		}
+		for i, serverGroup := range promxySection.ServerGroups {
+			serverGroupData, err := yamlv2.Marshal(serverGroup)
+			if err != nil {
+				return err
+			}
+			if err := validatePromxyServerGroupData(serverGroupData); err != nil {
+				return fmt.Errorf("promxy.server_groups[%d]: %w", i, err)
+			}
+		}
+	}
+
+	prometheusData, err := yamlv2.Marshal(prometheusSections)
+	if err != nil {
+		return err
+	}
+	_, err = promconfig.Load(string(prometheusData), slog.New(slog.DiscardHandler))
+	return err
 }
 
-type PromxyConfigServerGroup struct {
-	Targets               []string
-	PathPrefix            string
-	Scheme                string
-	DialTimeout           string
-	TlsInsecureSkipVerify bool
-	Username              string
-	Password              string
-	ClusterName           string
-	BasicAuthEnabled      bool
+func remarshalYAMLv2(in any, out any) error {
+	data, err := yamlv2.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return yamlv2.UnmarshalStrict(data, out)
 }
 
-func RenderPromxySecretTemplate(config *PromxyConfig) (string, error) {
-	t := template.Must(template.New("promxy-secret").Parse(promxySecretTemplate))
+func marshalYAML(in any) (string, error) {
 	var buf bytes.Buffer
-	err := t.Execute(&buf, config)
-	return buf.String(), err
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(in); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
 }
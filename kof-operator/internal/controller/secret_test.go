@@ -1,30 +1,79 @@
 package controller
 
 import (
+	"reflect"
+	"slices"
+	"strings"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	config_util "github.com/prometheus/common/config"
+	"gopkg.in/yaml.v3"
 )
 
+// Keys of `servergroup.Config` of promxy (pkg/servergroup/config.go) rendered by the operator.
+// promxy is not a module dependency, so `promxyServerGroupValidation` mirrors it:
+// update both when promxy adds, renames or removes a key.
+var promxyServerGroupConfigKeys = []string{
+	"anti_affinity",
+	"dns_sd_configs",
+	"http_client",
+	"ignore_error",
+	"kubernetes_sd_configs",
+	"labels",
+	"path_prefix",
+	"query_params",
+	"relabel_configs",
+	"remote_read",
+	"remote_read_path",
+	"scheme",
+	"static_configs",
+	"timeout",
+}
+
+// Returns the sorted yaml keys of the struct `t`, with the keys of inlined structs.
+func getYAMLKeys(t reflect.Type) []string {
+	keys := []string{}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if strings.Contains(options, "inline") {
+			keys = append(keys, getYAMLKeys(field.Type)...)
+			continue
+		}
+		if name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
 var _ = Describe("Make secret data", func() {
-	It("should produces a valid yaml for promxy secret config", func() {
-		config := &PromxyConfig{
-			RemoteWriteUrl: "http://vminsert-cluster:8480/insert/0/prometheus/api/v1/write",
-			ServerGroups: []*PromxyConfigServerGroup{
-				{
-					Targets:               []string{"vmauth.storage0.example.net:443"},
-					PathPrefix:            "/vm/select/0/prometheus/",
-					Scheme:                "https",
-					DialTimeout:           "1s",
-					Username:              "u",
-					Password:              "p",
-					ClusterName:           "test-cluster",
-					TlsInsecureSkipVerify: true,
-					BasicAuthEnabled:      true,
-				},
+	newServerGroup := func() *PromxyServerGroupConfig {
+		return &PromxyServerGroupConfig{
+			StaticConfigs: []*PromxyStaticConfig{{Targets: []string{"vmauth.storage0.example.net:443"}}},
+			PathPrefix:    "/vm/select/0/prometheus/",
+			Scheme:        "https",
+			HTTPClient: PromxyHTTPClientConfig{
+				DialTimeout: "1s",
+				TLSConfig:   &PromxyTLSConfig{InsecureSkipVerify: true},
+				BasicAuth:   &PromxyBasicAuth{Username: "u", Password: "p"},
 			},
+			Labels:      map[string]string{"promxyCluster": "test-cluster"},
+			IgnoreError: true,
 		}
-		data, err := RenderPromxySecretTemplate(config)
+	}
+
+	It("should produces a valid yaml for promxy secret config", func() {
+		config := NewPromxyConfig("http://vminsert-cluster:8480/insert/0/prometheus/api/v1/write")
+		config.Promxy.ServerGroups = append(config.Promxy.ServerGroups, newServerGroup())
+		data, configHash, err := RenderPromxyConfig(config)
 		Expect(err).ToNot(HaveOccurred())
+		Expect(ValidatePromxyConfig(data)).To(Succeed())
+		unmarked, err := marshalYAML(config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(configHash).To(Equal(PromxyConfigHash(unmarked)))
 		Expect("\n" + data).To(Equal(`
 global:
   evaluation_interval: 5s
@@ -33,30 +82,191 @@ global:
 rule_files:
   - /etc/promxy/rules/*.yaml
 alerting:
+  alert_relabel_configs:
+    - regex: kof_promxy_config_hash_` + configHash + `
+      action: labeldrop
   alertmanagers:
     - scheme: http
       static_configs:
         - targets:
-          - vmalertmanager-cluster:9093
+            - vmalertmanager-cluster:9093
 remote_write:
-  - url: "http://vminsert-cluster:8480/insert/0/prometheus/api/v1/write"
+  - url: http://vminsert-cluster:8480/insert/0/prometheus/api/v1/write
 promxy:
   server_groups:
     - static_configs:
         - targets:
-          - "vmauth.storage0.example.net:443"
-      path_prefix: "/vm/select/0/prometheus/"
-      scheme: "https"
+            - vmauth.storage0.example.net:443
+      path_prefix: /vm/select/0/prometheus/
+      scheme: https
       http_client:
-        dial_timeout: "1s"
+        dial_timeout: 1s
         tls_config:
           insecure_skip_verify: true
         basic_auth:
-          username: "u"
-          password: "p"
+          username: u
+          password: p
       labels:
-        promxyCluster: "test-cluster"
+        promxyCluster: test-cluster
       ignore_error: true
 `))
 	})
+
+	It("should escape special characters in credentials", func() {
+		const password = "p\"\n  injected: true\n#"
+		serverGroup := newServerGroup()
+		serverGroup.HTTPClient.BasicAuth.Password = password
+		config := NewPromxyConfig("http://vminsert-cluster:8480/insert/0/prometheus/api/v1/write")
+		config.Promxy.ServerGroups = append(config.Promxy.ServerGroups, serverGroup)
+		data, _, err := RenderPromxyConfig(config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ValidatePromxyConfig(data)).To(Succeed())
+
+		parsed := &PromxyConfig{}
+		Expect(yaml.Unmarshal([]byte(data), parsed)).To(Succeed())
+		Expect(parsed.Promxy.ServerGroups).To(HaveLen(1))
+		Expect(parsed.Promxy.ServerGroups[0].HTTPClient.BasicAuth.Password).To(Equal(password))
+	})
+
+	It("should keep the server group mirror in parity with promxy", func() {
+		Expect(getYAMLKeys(reflect.TypeOf(promxyServerGroupValidation{}))).To(Equal(promxyServerGroupConfigKeys))
+		Expect(getYAMLKeys(reflect.TypeOf(PromxyServerGroupConfig{}))).To(Equal(promxyServerGroupConfigKeys))
+
+		By("rendering only the http_client keys of promxy, which inlines the upstream HTTP client config")
+		httpClientKeys := append(getYAMLKeys(reflect.TypeOf(config_util.HTTPClientConfig{})), "dial_timeout")
+		Expect(httpClientKeys).To(ContainElements(getYAMLKeys(reflect.TypeOf(PromxyHTTPClientConfig{}))))
+	})
+
+	It("should reject invalid server groups", func() {
+		serverGroup := newServerGroup()
+		serverGroup.Scheme = "ftp"
+		Expect(ValidatePromxyServerGroup(serverGroup)).To(MatchError(ContainSubstring("invalid scheme")))
+
+		serverGroup = newServerGroup()
+		serverGroup.HTTPClient.DialTimeout = "soon"
+		Expect(ValidatePromxyServerGroup(serverGroup)).NotTo(Succeed())
+
+		serverGroup = newServerGroup()
+		serverGroup.HTTPClient.Authorization = &PromxyAuthorization{Type: "Bearer", Credentials: "t"}
+		Expect(ValidatePromxyServerGroup(serverGroup)).To(MatchError(ContainSubstring("at most one")))
+	})
+
+	It("should render service discovery of server groups", func() {
+		serverGroup := newServerGroup()
+		serverGroup.StaticConfigs = nil
+		serverGroup.DNSSDConfigs = []*PromxyDNSSDConfig{{
+			Names: []string{"_http._tcp.vmselect.storage0.example.net"},
+		}, {
+			Names: []string{"vmselect.storage0.example.net"},
+			Type:  "A",
+			Port:  8481,
+		}}
+		serverGroup.KubernetesSDConfigs = []*PromxyKubernetesSDConfig{{
+			Role:       "endpointslice",
+			Namespaces: &PromxyKubernetesSDNamespaces{Names: []string{"kof"}},
+			Selectors: []*PromxyKubernetesSDSelector{{
+				Role:  "service",
+				Label: "app.kubernetes.io/name=vmselect",
+			}},
+		}}
+		Expect(ValidatePromxyServerGroup(serverGroup)).To(Succeed())
+
+		config := NewPromxyConfig("http://vminsert-cluster:8480/insert/0/prometheus/api/v1/write")
+		config.Promxy.ServerGroups = append(config.Promxy.ServerGroups, serverGroup)
+		data, _, err := RenderPromxyConfig(config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ValidatePromxyConfig(data)).To(Succeed())
+		Expect(data).To(ContainSubstring(`
+    - dns_sd_configs:
+        - names:
+            - _http._tcp.vmselect.storage0.example.net
+        - names:
+            - vmselect.storage0.example.net
+          type: A
+          port: 8481
+      kubernetes_sd_configs:
+        - role: endpointslice
+          namespaces:
+            names:
+              - kof
+          selectors:
+            - role: service
+              label: app.kubernetes.io/name=vmselect
+      path_prefix:`))
+	})
+
+	It("should reject invalid service discovery of server groups", func() {
+		serverGroup := newServerGroup()
+		serverGroup.DNSSDConfigs = []*PromxyDNSSDConfig{{Names: []string{"vmselect"}, Type: "A"}}
+		Expect(ValidatePromxyServerGroup(serverGroup)).To(MatchError(ContainSubstring("port is required")))
+
+		serverGroup = newServerGroup()
+		serverGroup.KubernetesSDConfigs = []*PromxyKubernetesSDConfig{{Role: "node"}}
+		Expect(ValidatePromxyServerGroup(serverGroup)).To(MatchError(ContainSubstring("invalid role")))
+
+		serverGroup = newServerGroup()
+		serverGroup.KubernetesSDConfigs = []*PromxyKubernetesSDConfig{{
+			Role:      "pod",
+			Selectors: []*PromxyKubernetesSDSelector{{Role: "service"}},
+		}}
+		Expect(ValidatePromxyServerGroup(serverGroup)).To(MatchError(ContainSubstring("supports only")))
+
+		serverGroup = newServerGroup()
+		serverGroup.KubernetesSDConfigs = []*PromxyKubernetesSDConfig{{
+			Role:      "pod",
+			Selectors: []*PromxyKubernetesSDSelector{{Role: "pod", Label: "app in ("}},
+		}}
+		Expect(ValidatePromxyServerGroup(serverGroup)).NotTo(Succeed())
+	})
+
+	It("should render tuning of server groups", func() {
+		serverGroup := newServerGroup()
+		serverGroup.Labels["region"] = "eu"
+		serverGroup.RelabelConfigs = []*PromxyRelabelConfig{{
+			SourceLabels: []string{"__meta_kubernetes_endpoint_port_name"},
+			Regex:        "http",
+			Action:       "keep",
+		}}
+		serverGroup.QueryParams = map[string]string{"nocache": "1"}
+		serverGroup.RemoteRead = true
+		serverGroup.AntiAffinity = "10s"
+		serverGroup.Timeout = "30s"
+		serverGroup.IgnoreError = false
+		Expect(ValidatePromxyServerGroup(serverGroup)).To(Succeed())
+
+		data, err := marshalYAML(serverGroup)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(HaveSuffix(`
+labels:
+  promxyCluster: test-cluster
+  region: eu
+relabel_configs:
+  - source_labels: [__meta_kubernetes_endpoint_port_name]
+    regex: http
+    action: keep
+query_params:
+  nocache: "1"
+remote_read: true
+anti_affinity: 10s
+timeout: 30s
+ignore_error: false
+`))
+	})
+
+	It("should reject invalid tuning of server groups", func() {
+		serverGroup := newServerGroup()
+		serverGroup.RelabelConfigs = []*PromxyRelabelConfig{{Action: "replace"}}
+		Expect(ValidatePromxyServerGroup(serverGroup)).To(MatchError(ContainSubstring("target_label")))
+
+		serverGroup = newServerGroup()
+		serverGroup.Timeout = "soon"
+		Expect(ValidatePromxyServerGroup(serverGroup)).NotTo(Succeed())
+	})
+
+	It("should reject invalid promxy config", func() {
+		config := NewPromxyConfig("://vminsert-cluster")
+		data, _, err := RenderPromxyConfig(config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ValidatePromxyConfig(data)).NotTo(Succeed())
+	})
 })
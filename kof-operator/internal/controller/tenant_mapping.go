@@ -0,0 +1,226 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+
+	kcmv1beta1 "github.com/K0rdent/kcm/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+// Optional ConfigMap in the release namespace, mapping ClusterDeployments to tenants.
+const KofTenantMappingConfigMapName = "kof-tenant-mapping"
+const TenantMappingKey = "mapping"
+
+// Selects the tenant of a ClusterDeployment by name, on top of its namespace.
+// Set by the operator on the objects generated for the tenant too.
+const KofTenantLabel = prefix + "kof-tenant"
+
+// Added by promxy to the series of the PromxyServerGroups of a tenant, to filter them in the shared promxy.
+const TenantSeriesLabel = "tenant"
+
+// Tenant isolates the telemetry of its ClusterDeployments in VictoriaMetrics and VictoriaLogs
+// of the shared regional clusters. ClusterDeployments without a tenant use the default tenant 0.
+type Tenant struct {
+	// Name of the tenant, used in `KofTenantLabel` and in the names of the objects generated for it.
+	Name string `json:"name"`
+
+	// AccountID of the tenant in VictoriaMetrics and VictoriaLogs.
+	AccountID uint32 `json:"accountID"`
+
+	// ProjectID of the tenant in VictoriaMetrics and VictoriaLogs, 0 by default.
+	ProjectID uint32 `json:"projectID,omitempty"`
+
+	// Namespaces of the ClusterDeployments of the tenant, unless they select another tenant by label.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// PromxySecretName is the promxy config Secret of the PromxyServerGroups of the tenant,
+	// the one mounted by promxy of the chart by default and the only one allowed,
+	// as the series of tenants are told apart by `TenantSeriesLabel` in the same promxy.
+	PromxySecretName string `json:"promxySecretName,omitempty"`
+}
+
+// Returns the tenants from the `KofTenantMappingConfigMapName` ConfigMap,
+// or nil if it is not found.
+func readTenants(ctx context.Context, reader client.Reader) ([]Tenant, error) {
+	log := log.FromContext(ctx)
+
+	releaseNamespace, ok := os.LookupEnv("RELEASE_NAMESPACE")
+	if !ok {
+		return nil, fmt.Errorf("required RELEASE_NAMESPACE env var is not set")
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := reader.Get(ctx, types.NamespacedName{
+		Name:      KofTenantMappingConfigMapName,
+		Namespace: releaseNamespace,
+	}, configMap); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		log.Error(
+			err, "cannot read tenant mapping ConfigMap",
+			"configMapName", KofTenantMappingConfigMapName,
+		)
+		return nil, err
+	}
+
+	tenants, err := parseTenants(configMap.Data[TenantMappingKey])
+	if err != nil {
+		log.Error(
+			err, "invalid tenant mapping",
+			"configMapName", KofTenantMappingConfigMapName,
+			"key", TenantMappingKey,
+		)
+		return nil, err
+	}
+	return tenants, nil
+}
+
+func parseTenants(data string) ([]Tenant, error) {
+	var tenants []Tenant
+	if err := yaml.UnmarshalStrict([]byte(data), &tenants); err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	ids := map[string]bool{}
+	namespaces := map[string]bool{}
+	for i, tenant := range tenants {
+		if msgs := validation.IsDNS1123Label(tenant.Name); len(msgs) > 0 {
+			return nil, fmt.Errorf("tenant %d: invalid name %q: %s", i, tenant.Name, msgs[0])
+		}
+		if names[tenant.Name] {
+			return nil, fmt.Errorf("tenant %d: duplicate name %q", i, tenant.Name)
+		}
+		names[tenant.Name] = true
+
+		id := tenant.metricsTenantID()
+		if id == "0" {
+			return nil, fmt.Errorf("tenant %d: accountID or projectID is required, 0 is the default tenant", i)
+		}
+		if ids[id] {
+			return nil, fmt.Errorf("tenant %d: duplicate accountID and projectID %q", i, id)
+		}
+		ids[id] = true
+
+		for _, namespace := range tenant.Namespaces {
+			if namespaces[namespace] {
+				return nil, fmt.Errorf("tenant %d: namespace %q is mapped to another tenant", i, namespace)
+			}
+			namespaces[namespace] = true
+		}
+
+		if tenant.PromxySecretName != "" && tenant.PromxySecretName != GetServedPromxySecretName() {
+			return nil, fmt.Errorf(
+				"tenant %d: promxySecretName %q is not served by promxy, which mounts Secret %q",
+				i, tenant.PromxySecretName, GetServedPromxySecretName(),
+			)
+		}
+	}
+	return tenants, nil
+}
+
+// Returns the tenant selected by `KofTenantLabel` of the ClusterDeployment or mapped to its namespace,
+// or nil for the default tenant.
+func findTenant(
+	ctx context.Context,
+	reader client.Reader,
+	clusterDeployment *kcmv1beta1.ClusterDeployment,
+) (*Tenant, error) {
+	tenants, err := readTenants(ctx, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantName, hasTenantLabel := clusterDeployment.Labels[KofTenantLabel]
+	for _, tenant := range tenants {
+		if hasTenantLabel && tenant.Name == tenantName ||
+			!hasTenantLabel && slices.Contains(tenant.Namespaces, clusterDeployment.Namespace) {
+			return &tenant, nil
+		}
+	}
+	if !hasTenantLabel {
+		return nil, nil
+	}
+	return nil, fmt.Errorf(
+		`%w: tenant %q of label "%s" in ConfigMap %q`,
+		errTenantNotFound, tenantName, KofTenantLabel, KofTenantMappingConfigMapName,
+	)
+}
+
+var errTenantNotFound = fmt.Errorf("tenant is not found")
+
+// Returns the tenant in the `accountID[:projectID]` form of VictoriaMetrics URLs.
+func (t *Tenant) metricsTenantID() string {
+	if t.ProjectID == 0 {
+		return strconv.FormatUint(uint64(t.AccountID), 10)
+	}
+	return fmt.Sprintf("%d:%d", t.AccountID, t.ProjectID)
+}
+
+// Returns the VictoriaLogs headers selecting the tenant.
+func (t *Tenant) logsHeaders() map[string]string {
+	return map[string]string{
+		"AccountID": strconv.FormatUint(uint64(t.AccountID), 10),
+		"ProjectID": strconv.FormatUint(uint64(t.ProjectID), 10),
+	}
+}
+
+func (t *Tenant) promxySecretName() string {
+	if t.PromxySecretName != "" {
+		return t.PromxySecretName
+	}
+	return GetServedPromxySecretName()
+}
+
+// GetServedPromxySecretName returns the promxy config Secret mounted by promxy of the chart.
+func GetServedPromxySecretName() string {
+	if secretName, ok := os.LookupEnv("PROMXY_SECRET_NAME"); ok && secretName != "" {
+		return secretName
+	}
+	return DefaultPromxySecretName
+}
+
+// Matches the tenant path segment of VictoriaMetrics cluster URLs, e.g. `/insert/0/` or `/select/0/`.
+var metricsTenantPathRegexp = regexp.MustCompile(`/(insert|select)/\d+(:\d+)?(/|$)`)
+
+// Returns the metrics `endpoint` with the tenant path segment set to `tenant`,
+// or the `endpoint` as is for the default nil `tenant`.
+func setMetricsTenant(endpoint string, tenant *Tenant) (string, error) {
+	if tenant == nil {
+		return endpoint, nil
+	}
+	location := metricsTenantPathRegexp.FindStringSubmatchIndex(endpoint)
+	if location == nil {
+		return "", fmt.Errorf("cannot set tenant in metrics endpoint %q without /insert/0/ or /select/0/ path", endpoint)
+	}
+	action := endpoint[location[2]:location[3]]
+	return endpoint[:location[0]] + "/" + action + "/" + tenant.metricsTenantID() + endpoint[location[6]:], nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tenant mapping", func() {
+	DescribeTable("should set tenant in metrics endpoints",
+		func(endpoint string, tenant *Tenant, expected string) {
+			Expect(setMetricsTenant(endpoint, tenant)).To(Equal(expected))
+		},
+		Entry("default tenant",
+			"https://vmauth.kof.example.com/vm/insert/0/prometheus/api/v1/write", nil,
+			"https://vmauth.kof.example.com/vm/insert/0/prometheus/api/v1/write"),
+		Entry("write endpoint",
+			"https://vmauth.kof.example.com/vm/insert/0/prometheus/api/v1/write", &Tenant{AccountID: 42},
+			"https://vmauth.kof.example.com/vm/insert/42/prometheus/api/v1/write"),
+		Entry("read endpoint with project",
+			"https://vmauth.kof.example.com/vm/select/0/prometheus", &Tenant{AccountID: 42, ProjectID: 7},
+			"https://vmauth.kof.example.com/vm/select/42:7/prometheus"),
+		Entry("istio endpoint",
+			"http://regional-vmselect:8481/select/0/prometheus", &Tenant{AccountID: 42},
+			"http://regional-vmselect:8481/select/42/prometheus"),
+	)
+
+	It("should reject metrics endpoint without tenant path", func() {
+		_, err := setMetricsTenant("https://vmsingle.kof.example.com/prometheus", &Tenant{AccountID: 42})
+		Expect(err).To(MatchError(ContainSubstring("cannot set tenant")))
+	})
+
+	It("should return logs headers and promxy secret name of tenant", func() {
+		tenant := &Tenant{Name: "team-a", AccountID: 42}
+		Expect(tenant.logsHeaders()).To(Equal(map[string]string{"AccountID": "42", "ProjectID": "0"}))
+		Expect(tenant.promxySecretName()).To(Equal(DefaultPromxySecretName))
+
+		GinkgoT().Setenv("PROMXY_SECRET_NAME", "kof-promxy-config")
+		Expect(tenant.promxySecretName()).To(Equal("kof-promxy-config"))
+	})
+
+	DescribeTable("should reject invalid tenants",
+		func(data string, message string) {
+			_, err := parseTenants(data)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("invalid name", `[{"name": "Team A", "accountID": 1}]`, "invalid name"),
+		Entry("default tenant", `[{"name": "team-a"}]`, "0 is the default tenant"),
+		Entry("duplicate name", `[{"name": "team-a", "accountID": 1}, {"name": "team-a", "accountID": 2}]`,
+			"duplicate name"),
+		Entry("duplicate account", `[{"name": "team-a", "accountID": 1}, {"name": "team-b", "accountID": 1}]`,
+			"duplicate accountID"),
+		Entry("shared namespace", `[
+			{"name": "team-a", "accountID": 1, "namespaces": ["teams"]},
+			{"name": "team-b", "accountID": 2, "namespaces": ["teams"]}
+		]`, "is mapped to another tenant"),
+		Entry("unknown field", `[{"name": "team-a", "account": 1}]`, "unknown field"),
+		Entry("promxy secret not served", `[{"name": "team-a", "accountID": 1, "promxySecretName": "team-a-promxy"}]`,
+			"is not served by promxy"),
+	)
+})